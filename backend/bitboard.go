@@ -235,6 +235,10 @@ var (
 	KnightAttacks, KingAttacks [64]uint64
 )
 
+// BetweenBB contains squares strictly between two aligned squares
+// LineBB contains whole line that goes through two aligned squares
+var BetweenBB, LineBB [64][64]uint64
+
 // Least significant bit
 func BitScan(bb uint64) int {
 	return bits.TrailingZeros64(bb)
@@ -374,12 +378,29 @@ func init() {
 	initArray(&rookBlockerMask, generateRookBlockerMask)
 	rookBlockerBoard := initRookBlockerBoard()
 	initRookMoveBoard(rookBlockerBoard)
-	initRookMagicIndex(rookBlockerBoard)
 	initRookAttacks(rookBlockerBoard)
 
 	initArray(&bishopBlockerMask, generateBishopBlockerMask)
 	bishopBlockerBoard := initBishopBlockerBoard()
 	initBishopMoveBoard(bishopBlockerBoard)
-	initBishopMagicIndex(bishopBlockerBoard)
 	initBishopAttacks(bishopBlockerBoard)
+
+	initLines()
+}
+
+func initLines() {
+	for from := 0; from < 64; from++ {
+		for to := 0; to < 64; to++ {
+			if from == to {
+				continue
+			}
+			if RookAttacks(from, 0)&SquareMask[to] != 0 {
+				BetweenBB[from][to] = RookAttacks(from, SquareMask[to]) & RookAttacks(to, SquareMask[from])
+				LineBB[from][to] = (RookAttacks(from, 0) & RookAttacks(to, 0)) | SquareMask[from] | SquareMask[to]
+			} else if BishopAttacks(from, 0)&SquareMask[to] != 0 {
+				BetweenBB[from][to] = BishopAttacks(from, SquareMask[to]) & BishopAttacks(to, SquareMask[from])
+				LineBB[from][to] = (BishopAttacks(from, 0) & BishopAttacks(to, 0)) | SquareMask[from] | SquareMask[to]
+			}
+		}
+	}
 }
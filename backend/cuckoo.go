@@ -0,0 +1,76 @@
+package backend
+
+// Cuckoo tables for upcoming repetition detection
+// Based on "Detecting upcoming repetitions" by Marcel van Kervinck
+// Every reversible move of a non pawn piece is stored under zobrist difference
+// of positions before and after the move.
+
+const cuckooSize = 8192
+
+var cuckooKeys [cuckooSize]uint64
+var cuckooMoves [cuckooSize]Move
+
+func cuckooH1(key uint64) int {
+	return int(key & (cuckooSize - 1))
+}
+
+func cuckooH2(key uint64) int {
+	return int((key >> 16) & (cuckooSize - 1))
+}
+
+func initCuckoo() {
+	for piece := Knight; piece <= King; piece++ {
+		for side := Black; side <= White; side++ {
+			for from := 0; from < 64; from++ {
+				for to := from + 1; to < 64; to++ {
+					if pieceAttacks(piece, from)&SquareMask[to] == 0 {
+						continue
+					}
+					move := NewMove(from, to, piece, None, QuietMove)
+					key := zobrist[piece][side][from] ^ zobrist[piece][side][to] ^ zobristColor
+					idx := cuckooH1(key)
+					// Insert into table, kicking out existing entries
+					for {
+						cuckooKeys[idx], key = key, cuckooKeys[idx]
+						cuckooMoves[idx], move = move, cuckooMoves[idx]
+						if move == NullMove {
+							break
+						}
+						if idx == cuckooH1(key) {
+							idx = cuckooH2(key)
+						} else {
+							idx = cuckooH1(key)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func pieceAttacks(piece, square int) uint64 {
+	switch piece {
+	case Knight:
+		return KnightAttacks[square]
+	case Bishop:
+		return BishopAttacks(square, 0)
+	case Rook:
+		return RookAttacks(square, 0)
+	case Queen:
+		return QueenAttacks(square, 0)
+	case King:
+		return KingAttacks[square]
+	}
+	return 0
+}
+
+// CuckooMove returns reversible move that changes position key by moveKey
+func CuckooMove(moveKey uint64) (bool, Move) {
+	if idx := cuckooH1(moveKey); cuckooKeys[idx] == moveKey {
+		return true, cuckooMoves[idx]
+	}
+	if idx := cuckooH2(moveKey); cuckooKeys[idx] == moveKey {
+		return true, cuckooMoves[idx]
+	}
+	return false, NullMove
+}
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"errors"
 	"github.com/mhib/combusken/utils"
 	"strconv"
 	"strings"
@@ -64,6 +65,64 @@ func ParseFen(input string) Position {
 	return res
 }
 
+// ValidateFen checks that fen describes a position that ParseFen can handle and search can start from
+func ValidateFen(fen string) error {
+	fields := strings.Split(fen, " ")
+	if len(fields) < 3 {
+		return errors.New("FEN has to contain placement, side to move and castling rights")
+	}
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return errors.New("FEN placement has to contain 8 ranks")
+	}
+	kings := [2]int{}
+	for i, rank := range ranks {
+		squares := 0
+		for _, char := range rank {
+			if char >= '1' && char <= '8' {
+				squares += int(char - '0')
+				continue
+			}
+			if !strings.ContainsRune("pnbrqkPNBRQK", char) {
+				return errors.New("Invalid piece " + string(char))
+			}
+			if char == 'k' || char == 'K' {
+				kings[utils.BoolToInt(char == 'K')]++
+			}
+			if (char == 'p' || char == 'P') && (i == 0 || i == 7) {
+				return errors.New("Pawn on the first or the last rank")
+			}
+			squares++
+		}
+		if squares != 8 {
+			return errors.New("Rank " + rank + " does not contain 8 squares")
+		}
+	}
+	if kings != [2]int{1, 1} {
+		return errors.New("Each side has to have exactly one king")
+	}
+	if fields[1] != "w" && fields[1] != "b" {
+		return errors.New("Invalid side to move " + fields[1])
+	}
+	for _, char := range fields[2] {
+		if !strings.ContainsRune("KQkq-", char) {
+			return errors.New("Invalid castling rights " + fields[2])
+		}
+	}
+	if len(fields) >= 4 && fields[3] != "-" {
+		if ep := fields[3]; len(ep) != 2 || ep[0] < 'a' || ep[0] > 'h' || (ep[1] != '3' && ep[1] != '6') {
+			return errors.New("Invalid en passant square " + ep)
+		}
+	}
+	pos := ParseFen(fen)
+	// Side that is not to move cannot be in check
+	pos.SideToMove ^= 1
+	if pos.IsInCheck() {
+		return errors.New("Side not to move is in check")
+	}
+	return nil
+}
+
 func insertPiece(pos *Position, piece rune, bit uint64) {
 	pos.Colours[utils.BoolToInt(unicode.IsUpper(piece))] |= bit
 	switch byte(unicode.ToLower(piece)) {
@@ -81,3 +140,74 @@ func insertPiece(pos *Position, piece rune, bit uint64) {
 		pos.Pieces[King] |= bit
 	}
 }
+
+// Fen returns position in Forsyth-Edwards notation, move number is not tracked so it is always 1
+func (pos *Position) Fen() string {
+	var res strings.Builder
+	for y := 7; y >= 0; y-- {
+		empty := 0
+		for x := 0; x < 8; x++ {
+			piece := pieceLetter(pos, SquareMask[y*8+x])
+			if piece == 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				res.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			res.WriteByte(piece)
+		}
+		if empty > 0 {
+			res.WriteString(strconv.Itoa(empty))
+		}
+		if y > 0 {
+			res.WriteByte('/')
+		}
+	}
+
+	if pos.SideToMove == White {
+		res.WriteString(" w ")
+	} else {
+		res.WriteString(" b ")
+	}
+
+	if pos.Flags&(WhiteKingSideCastleFlag|WhiteQueenSideCastleFlag|BlackKingSideCastleFlag|BlackQueenSideCastleFlag) ==
+		WhiteKingSideCastleFlag|WhiteQueenSideCastleFlag|BlackKingSideCastleFlag|BlackQueenSideCastleFlag {
+		res.WriteByte('-')
+	} else {
+		for i, flag := range []uint8{WhiteKingSideCastleFlag, WhiteQueenSideCastleFlag, BlackKingSideCastleFlag, BlackQueenSideCastleFlag} {
+			if pos.Flags&flag == 0 {
+				res.WriteByte("KQkq"[i])
+			}
+		}
+	}
+
+	if pos.EpSquare == 0 {
+		res.WriteString(" -")
+	} else {
+		// EpSquare holds square of the pawn that moved, notation uses square behind it
+		square := pos.EpSquare - 8
+		if pos.SideToMove == White {
+			square = pos.EpSquare + 8
+		}
+		res.WriteByte(' ')
+		res.WriteString(SquareString[square])
+	}
+
+	res.WriteString(" " + strconv.Itoa(pos.FiftyMove) + " 1")
+	return res.String()
+}
+
+func pieceLetter(pos *Position, bit uint64) byte {
+	var letter byte
+	for piece, char := range "pnbrqk" {
+		if pos.Pieces[piece]&bit != 0 {
+			letter = byte(char)
+		}
+	}
+	if letter != 0 && pos.Colours[White]&bit != 0 {
+		letter = byte(unicode.ToUpper(rune(letter)))
+	}
+	return letter
+}
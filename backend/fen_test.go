@@ -0,0 +1,45 @@
+package backend
+
+import "testing"
+
+func TestFen(t *testing.T) {
+	for _, fen := range []string{
+		InitialPositionFen,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b Kq e3 0 1",
+		"rnbqkbnr/pp2pppp/8/2ppP3/8/8/PPPP1PPP/RNBQKBNR w kq d6 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 12 1",
+	} {
+		pos := ParseFen(fen)
+		if res := pos.Fen(); res != fen {
+			t.Errorf("Expected %s, got %s", fen, res)
+		}
+	}
+}
+
+func TestValidateFen(t *testing.T) {
+	for _, fen := range []string{
+		InitialPositionFen,
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - -",
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b Kq e3 0 1",
+	} {
+		if err := ValidateFen(fen); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", fen, err)
+		}
+	}
+	for _, fen := range []string{
+		"",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQQBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e4 0 1",
+		"pnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"4k2R/8/8/8/8/8/8/4K3 w - - 0 1",
+	} {
+		if err := ValidateFen(fen); err == nil {
+			t.Errorf("Expected %s to be invalid", fen)
+		}
+	}
+}
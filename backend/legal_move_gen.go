@@ -0,0 +1,364 @@
+package backend
+
+// Legal move generation
+// Pinned pieces and checkers are computed once per position,
+// so generated moves do not have to be verified with MakeMove.
+
+// AttackersTo returns pieces of both sides attacking square with given occupancy
+func (pos *Position) AttackersTo(square int, occupancy uint64) uint64 {
+	return (PawnAttacks[White][square] & pos.Pieces[Pawn] & pos.Colours[Black]) |
+		(PawnAttacks[Black][square] & pos.Pieces[Pawn] & pos.Colours[White]) |
+		(KnightAttacks[square] & pos.Pieces[Knight]) |
+		(KingAttacks[square] & pos.Pieces[King]) |
+		(BishopAttacks(square, occupancy) & (pos.Pieces[Bishop] | pos.Pieces[Queen])) |
+		(RookAttacks(square, occupancy) & (pos.Pieces[Rook] | pos.Pieces[Queen]))
+}
+
+// Checkers returns enemy pieces giving check to side to move
+func (pos *Position) Checkers() uint64 {
+	kingSquare := BitScan(pos.Pieces[King] & pos.Colours[pos.SideToMove])
+	return pos.AttackersTo(kingSquare, pos.Colours[White]|pos.Colours[Black]) & pos.Colours[pos.SideToMove^1]
+}
+
+// PinnedPieces returns pieces of side to move that are pinned to their king
+func (pos *Position) PinnedPieces() (pinned uint64) {
+	us := pos.Colours[pos.SideToMove]
+	them := pos.Colours[pos.SideToMove^1]
+	occupancy := us | them
+	kingSquare := BitScan(pos.Pieces[King] & us)
+	snipers := ((RookAttacks(kingSquare, 0) & (pos.Pieces[Rook] | pos.Pieces[Queen])) |
+		(BishopAttacks(kingSquare, 0) & (pos.Pieces[Bishop] | pos.Pieces[Queen]))) & them
+	for ; snipers != 0; snipers &= snipers - 1 {
+		blockers := BetweenBB[kingSquare][BitScan(snipers)] & occupancy
+		if OnlyOne(blockers) && blockers&us != 0 {
+			pinned |= blockers
+		}
+	}
+	return
+}
+
+type legalityInfo struct {
+	kingSquare int
+	pinned     uint64
+	checkers   uint64
+	// Squares non king pieces may move to
+	target uint64
+}
+
+func newLegalityInfo(pos *Position) (res legalityInfo) {
+	res.kingSquare = BitScan(pos.Pieces[King] & pos.Colours[pos.SideToMove])
+	res.pinned = pos.PinnedPieces()
+	res.checkers = pos.Checkers()
+	switch {
+	case res.checkers == 0:
+		res.target = ^uint64(0)
+	case MoreThanOne(res.checkers):
+		// Only king can move in double check
+		res.target = 0
+	default:
+		// Evasions: capture of checking piece or interposition
+		res.target = res.checkers | BetweenBB[res.kingSquare][BitScan(res.checkers)]
+	}
+	return
+}
+
+// Mask of squares piece from a given square may move to without exposing the king
+func (info *legalityInfo) pinMask(from int) uint64 {
+	if info.pinned&SquareMask[from] != 0 {
+		return LineBB[info.kingSquare][from]
+	}
+	return ^uint64(0)
+}
+
+func (pos *Position) isKingMoveLegal(from, to int) bool {
+	occupancy := (pos.Colours[White] | pos.Colours[Black]) ^ SquareMask[from]
+	return pos.AttackersTo(to, occupancy)&pos.Colours[pos.SideToMove^1] & ^SquareMask[to] == 0
+}
+
+// En passant captures may expose king on a rank, so they are verified by making the move
+func (pos *Position) isEpCaptureLegal(move Move) bool {
+	var child Position
+	return pos.MakeMove(move, &child)
+}
+
+// GenerateLegalNoisy generates legal captures and promotions in the same order as GenerateNoisy
+func GenerateLegalNoisy(pos *Position, buffer []EvaledMove) uint8 {
+	info := newLegalityInfo(pos)
+	return generateLegalNoisy(pos, &info, buffer)
+}
+
+// GenerateLegalQuiet generates legal quiet moves in the same order as GenerateQuiet
+func GenerateLegalQuiet(pos *Position, buffer []EvaledMove) uint8 {
+	info := newLegalityInfo(pos)
+	return generateLegalQuiet(pos, &info, buffer)
+}
+
+func generateLegalNoisy(pos *Position, info *legalityInfo, buffer []EvaledMove) (size uint8) {
+	var fromBB, toBB uint64
+	var fromId, toId, what int
+
+	sideToMove := pos.SideToMove
+	ourOccupation := pos.Colours[sideToMove]
+	theirOccupation := pos.Colours[sideToMove^1]
+	allOccupation := ourOccupation | theirOccupation
+	target := info.target
+
+	// PAWNS
+	forward := forwardByColor[sideToMove]
+	if pos.EpSquare != 0 && target != 0 {
+		fromBB = (SquareMask[uint(pos.EpSquare)-1] | SquareMask[uint(pos.EpSquare)+1]) &
+			epRankBB[sideToMove] & pos.Pieces[Pawn] & ourOccupation
+		for ; fromBB > 0; fromBB &= (fromBB - 1) {
+			fromId = BitScan(fromBB)
+			move := NewMove(fromId, pos.EpSquare+forward, Pawn, Pawn, NewType(1, 0, 0, 1))
+			if pos.isEpCaptureLegal(move) {
+				buffer[size].Move = move
+				size++
+			}
+		}
+	}
+	if sideToMove == White {
+		fromBB = BlackPawnsAttacks(theirOccupation) | RANK_7_BB
+	} else {
+		fromBB = WhitePawnsAttacks(theirOccupation) | RANK_2_BB
+	}
+	if target == 0 {
+		fromBB = 0
+	}
+	for fromBB &= pos.Pieces[Pawn] & ourOccupation; fromBB != 0; fromBB &= fromBB - 1 {
+		fromId = BitScan(fromBB)
+		mask := target & info.pinMask(fromId)
+		if Rank(fromId) == secondRank[sideToMove^1] {
+			toId = fromId + forward
+			if SquareMask[toId]&allOccupation == 0 && SquareMask[toId]&mask != 0 {
+				addPromotions(NewMove(fromId, toId, Pawn, None, 0), buffer[size:])
+				size += 4
+			}
+			for toBB = PawnAttacks[sideToMove][fromId] & theirOccupation & mask; toBB > 0; toBB &= (toBB - 1) {
+				toId = BitScan(toBB)
+				what = pos.TypeOnSquare(SquareMask[uint(toId)])
+				addPromotions(NewMove(fromId, toId, Pawn, what, 1), buffer[size:])
+				size += 4
+			}
+		} else {
+			for toBB = PawnAttacks[sideToMove][fromId] & theirOccupation & mask; toBB > 0; toBB &= (toBB - 1) {
+				toId = BitScan(toBB)
+				what = pos.TypeOnSquare(SquareMask[uint(toId)])
+				buffer[size].Move = NewMove(fromId, toId, Pawn, what, NewType(1, 0, 0, 0))
+				size++
+			}
+		}
+	}
+	// end of pawns
+
+	if target != 0 {
+		// Knights
+		// Pinned knight can never move
+		for fromBB = pos.Pieces[Knight] & ourOccupation & ^info.pinned; fromBB != 0; fromBB &= (fromBB - 1) {
+			fromId = BitScan(fromBB)
+			for toBB = KnightAttacks[fromId] & theirOccupation & target; toBB != 0; toBB &= (toBB - 1) {
+				toId = BitScan(toBB)
+				what = pos.TypeOnSquare(SquareMask[uint(toId)])
+				buffer[size].Move = NewMove(fromId, toId, Knight, what, NewType(1, 0, 0, 0))
+				size++
+			}
+		}
+		// end of knights
+
+		// Bishops
+		for fromBB = pos.Pieces[Bishop] & ourOccupation; fromBB != 0; fromBB &= (fromBB - 1) {
+			fromId = BitScan(fromBB)
+			for toBB = BishopAttacks(fromId, allOccupation) & theirOccupation & target & info.pinMask(fromId); toBB != 0; toBB &= (toBB - 1) {
+				toId = BitScan(toBB)
+				what = pos.TypeOnSquare(SquareMask[uint(toId)])
+				buffer[size].Move = NewMove(fromId, toId, Bishop, what, NewType(1, 0, 0, 0))
+				size++
+			}
+		}
+		// end of Bishops
+
+		// Rooks
+		for fromBB = pos.Pieces[Rook] & ourOccupation; fromBB != 0; fromBB &= (fromBB - 1) {
+			fromId = BitScan(fromBB)
+			for toBB = RookAttacks(fromId, allOccupation) & theirOccupation & target & info.pinMask(fromId); toBB != 0; toBB &= (toBB - 1) {
+				toId = BitScan(toBB)
+				what = pos.TypeOnSquare(SquareMask[uint(toId)])
+				buffer[size].Move = NewMove(fromId, toId, Rook, what, NewType(1, 0, 0, 0))
+				size++
+			}
+		}
+		// end of Rooks
+
+		// Queens
+		for fromBB = pos.Pieces[Queen] & ourOccupation; fromBB != 0; fromBB &= (fromBB - 1) {
+			fromId = BitScan(fromBB)
+			for toBB = QueenAttacks(fromId, allOccupation) & theirOccupation & target & info.pinMask(fromId); toBB != 0; toBB &= (toBB - 1) {
+				toId = BitScan(toBB)
+				what = pos.TypeOnSquare(SquareMask[uint(toId)])
+				buffer[size].Move = NewMove(fromId, toId, Queen, what, NewType(1, 0, 0, 0))
+				size++
+			}
+		}
+		// end of Queens
+	}
+
+	// Kings
+	fromId = info.kingSquare
+	for toBB = KingAttacks[fromId] & theirOccupation; toBB != 0; toBB &= (toBB - 1) {
+		toId = BitScan(toBB)
+		if !pos.isKingMoveLegal(fromId, toId) {
+			continue
+		}
+		what = pos.TypeOnSquare(SquareMask[uint(toId)])
+		buffer[size].Move = NewMove(fromId, toId, King, what, NewType(1, 0, 0, 0))
+		size++
+	}
+	// end of Kings
+
+	return
+}
+
+func generateLegalQuiet(pos *Position, info *legalityInfo, buffer []EvaledMove) (size uint8) {
+	var fromBB, toBB, toMask uint64
+	var fromId, toId int
+	sideToMove := pos.SideToMove
+	ourOccupation := pos.Colours[sideToMove]
+	theirOccupation := pos.Colours[sideToMove^1]
+	allOccupation := ourOccupation | theirOccupation
+	forward := forwardByColor[sideToMove]
+	target := info.target
+
+	if target != 0 {
+		for fromBB = pos.Pieces[Pawn] & ourOccupation & ^promotionBB[sideToMove]; fromBB > 0; fromBB &= (fromBB - 1) {
+			fromId = BitScan(fromBB)
+			mask := target & info.pinMask(fromId)
+			toId = fromId + forward
+			toMask = SquareMask[toId]
+			if allOccupation&toMask == 0 {
+				if toMask&mask != 0 {
+					buffer[size].Move = NewMove(fromId, toId, Pawn, None, 0)
+					size++
+				}
+
+				// Double pawn push
+				toId += forward
+				toMask = SquareMask[toId]
+				if Rank(fromId) == secondRank[sideToMove] && allOccupation&toMask == 0 && toMask&mask != 0 {
+					buffer[size].Move = NewMove(fromId, toId, Pawn, None, NewType(0, 0, 0, 1))
+					size++
+				}
+			}
+		}
+	}
+
+	// Castling
+	// Squares king passes through and lands on cannot be attacked
+	if info.checkers == 0 {
+		if pos.SideToMove == White {
+			if allOccupation&WHITE_KING_CASTLE_BLOCK_BB == 0 && pos.Flags&WhiteKingSideCastleFlag == 0 && !pos.IsSquareAttacked(F1, Black) && !pos.IsSquareAttacked(G1, Black) {
+				buffer[size].Move = WhiteKingSideCastle
+				size++
+			}
+			if allOccupation&WHITE_QUEEN_CASTLE_BLOCK_BB == 0 && pos.Flags&WhiteQueenSideCastleFlag == 0 && !pos.IsSquareAttacked(D1, Black) && !pos.IsSquareAttacked(C1, Black) {
+				buffer[size].Move = WhiteQueenSideCastle
+				size++
+			}
+		} else {
+			if allOccupation&BLACK_KING_CASTLE_BLOCK_BB == 0 && pos.Flags&BlackKingSideCastleFlag == 0 && !pos.IsSquareAttacked(F8, White) && !pos.IsSquareAttacked(G8, White) {
+				buffer[size].Move = BlackKingSideCastle
+				size++
+			}
+			if allOccupation&BLACK_QUEEN_CASTLE_BLOCK_BB == 0 && pos.Flags&BlackQueenSideCastleFlag == 0 && !pos.IsSquareAttacked(D8, White) && !pos.IsSquareAttacked(C8, White) {
+				buffer[size].Move = BlackQueenSideCastle
+				size++
+			}
+		}
+	}
+
+	// Knights
+	if target != 0 {
+		for fromBB = pos.Pieces[Knight] & ourOccupation & ^info.pinned; fromBB != 0; fromBB &= (fromBB - 1) {
+			fromId = BitScan(fromBB)
+			for toBB = KnightAttacks[fromId] & ^allOccupation & target; toBB != 0; toBB &= (toBB - 1) {
+				toId = BitScan(toBB)
+				buffer[size].Move = NewMove(fromId, toId, Knight, None, NewType(0, 0, 0, 0))
+				size++
+			}
+		}
+	}
+	// end of knights
+
+	// Kings
+	fromId = info.kingSquare
+	for toBB = KingAttacks[fromId] & ^allOccupation; toBB != 0; toBB &= (toBB - 1) {
+		toId = BitScan(toBB)
+		if !pos.isKingMoveLegal(fromId, toId) {
+			continue
+		}
+		buffer[size].Move = NewMove(fromId, toId, King, None, NewType(0, 0, 0, 0))
+		size++
+	}
+	// end of Kings
+
+	if target == 0 {
+		return
+	}
+
+	// Rooks
+	for fromBB = pos.Pieces[Rook] & ourOccupation; fromBB != 0; fromBB &= (fromBB - 1) {
+		fromId = BitScan(fromBB)
+		for toBB = RookAttacks(fromId, allOccupation) & ^allOccupation & target & info.pinMask(fromId); toBB != 0; toBB &= (toBB - 1) {
+			toId = BitScan(toBB)
+			buffer[size].Move = NewMove(fromId, toId, Rook, None, NewType(0, 0, 0, 0))
+			size++
+		}
+	}
+	// end of Rooks
+
+	// Bishops
+	for fromBB = pos.Pieces[Bishop] & ourOccupation; fromBB != 0; fromBB &= (fromBB - 1) {
+		fromId = BitScan(fromBB)
+		for toBB = BishopAttacks(fromId, allOccupation) & ^allOccupation & target & info.pinMask(fromId); toBB != 0; toBB &= (toBB - 1) {
+			toId = BitScan(toBB)
+			buffer[size].Move = NewMove(fromId, toId, Bishop, None, NewType(0, 0, 0, 0))
+			size++
+		}
+	}
+	// end of Bishops
+
+	// Queens
+	for fromBB = pos.Pieces[Queen] & ourOccupation; fromBB != 0; fromBB &= (fromBB - 1) {
+		fromId = BitScan(fromBB)
+		for toBB = QueenAttacks(fromId, allOccupation) & ^allOccupation & target & info.pinMask(fromId); toBB != 0; toBB &= (toBB - 1) {
+			toId = BitScan(toBB)
+			buffer[size].Move = NewMove(fromId, toId, Queen, None, NewType(0, 0, 0, 0))
+			size++
+		}
+	}
+	// end of Queens
+
+	return
+}
+
+// GenerateLegal generates all legal moves: noisy moves first and then quiet ones
+func GenerateLegal(pos *Position, buffer []EvaledMove) uint8 {
+	info := newLegalityInfo(pos)
+	noisySize := generateLegalNoisy(pos, &info, buffer)
+	return noisySize + generateLegalQuiet(pos, &info, buffer[noisySize:])
+}
+
+// LegalPerft is Perft that uses legal move generator and counts leaf nodes without making moves
+func LegalPerft(pos *Position, depth int) int {
+	var buffer [256]EvaledMove
+	size := GenerateLegal(pos, buffer[:])
+	if depth <= 1 {
+		return int(size)
+	}
+	result := 0
+	var child Position
+	for _, move := range buffer[:size] {
+		pos.MakeLegalMove(move.Move, &child)
+		result += LegalPerft(&child, depth-1)
+	}
+	return result
+}
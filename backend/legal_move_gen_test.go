@@ -0,0 +1,122 @@
+package backend
+
+import "testing"
+
+var legalTestFENs = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq -",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - -",
+	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	// En passant capture exposing king on a rank
+	"8/8/8/K1pP3r/8/8/8/7k w - c6 0 1",
+	// En passant capture of checking pawn
+	"8/8/8/2k5/3Pp3/8/8/4K3 b - d3 0 1",
+	// Double check
+	"4k3/8/8/8/8/5n2/8/r3K2R w K - 0 1",
+	// Castling through attacked square
+	"4k3/8/8/8/8/8/6r1/4K2R w K - 0 1",
+}
+
+func pseudoLegalMoves(pos *Position) map[Move]bool {
+	var buffer [256]EvaledMove
+	var child Position
+	noisySize := GenerateNoisy(pos, buffer[:])
+	quietsSize := GenerateQuiet(pos, buffer[noisySize:])
+	res := make(map[Move]bool)
+	for _, move := range buffer[:noisySize+quietsSize] {
+		if pos.MakeMove(move.Move, &child) {
+			res[move.Move] = true
+		}
+	}
+	return res
+}
+
+func compareWithPseudoLegal(t *testing.T, pos *Position, depth int) {
+	var buffer [256]EvaledMove
+	size := GenerateLegal(pos, buffer[:])
+	expected := pseudoLegalMoves(pos)
+	if int(size) != len(expected) {
+		t.Fatalf("Expected %d moves, got %d", len(expected), size)
+	}
+	for _, move := range buffer[:size] {
+		if !expected[move.Move] {
+			pos.Print()
+			t.Fatalf("Generated illegal move %v", move.Move)
+		}
+	}
+	if depth <= 1 {
+		return
+	}
+	var child Position
+	for _, move := range buffer[:size] {
+		pos.MakeLegalMove(move.Move, &child)
+		compareWithPseudoLegal(t, &child, depth-1)
+	}
+}
+
+func TestLegalMovesMatchPseudoLegal(t *testing.T) {
+	for _, fen := range legalTestFENs {
+		pos := ParseFen(fen)
+		compareWithPseudoLegal(t, &pos, 3)
+	}
+}
+
+func TestLegalPerft(t *testing.T) {
+	var tests = []struct {
+		fen   string
+		depth int
+		nodes int
+	}{
+		{legalTestFENs[0], 5, 4865609},
+		{legalTestFENs[1], 4, 4085603},
+		{legalTestFENs[2], 6, 11030083},
+		{legalTestFENs[3], 5, 15833292},
+		{legalTestFENs[4], 4, 2103487},
+		{legalTestFENs[5], 4, 3894594},
+	}
+	for i, test := range tests {
+		var p = ParseFen(test.fen)
+		if nodes := LegalPerft(&p, test.depth); nodes != test.nodes {
+			t.Error(i, test, nodes)
+		}
+		if nodes := Perft(&p, test.depth); nodes != test.nodes {
+			t.Error(i, test, nodes)
+		}
+	}
+}
+
+func TestGenerateQuietChecks(t *testing.T) {
+	fens := append(legalTestFENs,
+		// Discovered checks by pawn, knight and king
+		"4k3/8/8/8/4P3/8/8/K3R3 w - - 0 1",
+		"4k3/8/8/8/8/4N3/8/K3R3 w - - 0 1",
+		"7k/8/8/8/3K4/8/8/B7 w - - 0 1",
+	)
+	var buffer, checks [256]EvaledMove
+	var child Position
+	for _, fen := range fens {
+		pos := ParseFen(fen)
+		expected := make(map[Move]bool)
+		quietsSize := GenerateQuiet(&pos, buffer[:])
+		for _, move := range buffer[:quietsSize] {
+			if !move.IsCastling() && pos.MakeMove(move.Move, &child) && child.IsInCheck() {
+				expected[move.Move] = true
+			}
+		}
+		checksSize := GenerateQuietChecks(&pos, checks[:])
+		found := 0
+		for _, move := range checks[:checksSize] {
+			if pos.MakeMove(move.Move, &child) {
+				if !expected[move.Move] {
+					t.Errorf("%s: %v does not give check", fen, move.Move)
+				}
+				found++
+			}
+		}
+		if found != len(expected) {
+			t.Errorf("%s: expected %d checks, got %d", fen, len(expected), found)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package backend
 // Names as in https://stackoverflow.com/a/30862064
 // Pretty much everything as in this answer, but index right shift is done by constant values(bishopShift, rookShift)
 // Pseudo-random number generation from https://github.com/goutham/magic-bits
+// Magics are precomputed in magics.go, which is generated by tools/magics
+
+//go:generate go run ../tools/magics -o magics.go
 
 import (
 	"math/rand"
@@ -19,7 +22,6 @@ var (
 	rookMoveBoard                      [64][1 << MAX_ROOK_BITS]uint64
 	bishopMoveBoard                    [64][1 << MAX_BISHOP_BITS]uint64
 	bishopBlockerMask, rookBlockerMask [64]uint64
-	rookMagicIndex, bishopMagicIndex   [64]uint64
 )
 
 func generateRookBlockerMask(mask uint64) uint64 {
@@ -186,32 +188,20 @@ func initBishopMoveBoard(bishopBlockerBoard [][]uint64) {
 	}
 }
 
-func initRookMagicIndex(rookBlockerBoard [][]uint64) {
-	for idx := range rookBlockerBoard {
-		rookMagicIndex[idx] = findMagic(rookBlockerBoard[idx], rookMoveBoard[idx][:], rookShift)
-	}
-}
-
-func initBishopMagicIndex(bishopBlockerBoard [][]uint64) {
-	for idx := range bishopBlockerBoard {
-		bishopMagicIndex[idx] = findMagic(bishopBlockerBoard[idx], bishopMoveBoard[idx][:], bishopShift)
-	}
-}
-
-func u64rand() uint64 {
-	return (uint64(0xFFFF&rand.Uint32()) << 48) |
-		(uint64(0xFFFF&rand.Uint32()) << 32) |
-		(uint64(0xFFFF&rand.Uint32()) << 16) |
-		uint64(0xFFFF&rand.Uint32())
+func u64rand(r *rand.Rand) uint64 {
+	return (uint64(0xFFFF&r.Uint32()) << 48) |
+		(uint64(0xFFFF&r.Uint32()) << 32) |
+		(uint64(0xFFFF&r.Uint32()) << 16) |
+		uint64(0xFFFF&r.Uint32())
 }
 
-func biasedRandom() uint64 {
-	return u64rand() & u64rand() & u64rand()
+func biasedRandom(r *rand.Rand) uint64 {
+	return u64rand(r) & u64rand(r) & u64rand(r)
 }
 
-func findMagic(array []uint64, cmpArray []uint64, bits uint) uint64 {
+func findMagic(r *rand.Rand, array []uint64, cmpArray []uint64, bits uint) uint64 {
 	for {
-		magic := biasedRandom()
+		magic := biasedRandom(r)
 		others := make(map[uint64]int)
 		unique := true
 		for idx, el := range array {
@@ -251,3 +241,25 @@ func initBishopAttacks(bishopBlockerBoard [][]uint64) {
 	}
 	copy(bishopMoveBoard[:], bishopAttacks[:])
 }
+
+// FindMagics searches for magic numbers of every square.
+// It is used by tools/magics to generate magics.go file.
+func FindMagics(r *rand.Rand) (rookMagics, bishopMagics [64]uint64) {
+	rookBlockerBoard := initRookBlockerBoard()
+	bishopBlockerBoard := initBishopBlockerBoard()
+	for idx := range rookBlockerBoard {
+		moveBoard := make([]uint64, len(rookBlockerBoard[idx]))
+		for x, board := range rookBlockerBoard[idx] {
+			moveBoard[x] = generateRookMoveBoard(idx, board)
+		}
+		rookMagics[idx] = findMagic(r, rookBlockerBoard[idx], moveBoard, rookShift)
+	}
+	for idx := range bishopBlockerBoard {
+		moveBoard := make([]uint64, len(bishopBlockerBoard[idx]))
+		for x, board := range bishopBlockerBoard[idx] {
+			moveBoard[x] = generateBishopMoveBoard(idx, board)
+		}
+		bishopMagics[idx] = findMagic(r, bishopBlockerBoard[idx], moveBoard, bishopShift)
+	}
+	return
+}
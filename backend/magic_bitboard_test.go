@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"math/rand"
+	"testing"
+)
+
+var rookDirections = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+var bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+// Slow attacks generator that walks rays square by square
+func slowSlidingAttacks(square int, occupancy uint64, directions [4][2]int) (res uint64) {
+	for _, direction := range directions {
+		file, rank := File(square)+direction[0], Rank(square)+direction[1]
+		for file >= FILE_A && file <= FILE_H && rank >= RANK_1 && rank <= RANK_8 {
+			mask := SquareMask[rank*8+file]
+			res |= mask
+			if occupancy&mask != 0 {
+				break
+			}
+			file, rank = file+direction[0], rank+direction[1]
+		}
+	}
+	return
+}
+
+func TestMagicAttacks(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for square := 0; square < 64; square++ {
+		for _, blockers := range combinations(rookBlockerMask[square]) {
+			occupancy := blockers | (r.Uint64() & ^rookBlockerMask[square])
+			if RookAttacks(square, occupancy) != slowSlidingAttacks(square, occupancy, rookDirections) {
+				t.Fatalf("Invalid rook attacks on square %s with occupancy %x", SquareString[square], occupancy)
+			}
+		}
+		for _, blockers := range combinations(bishopBlockerMask[square]) {
+			occupancy := blockers | (r.Uint64() & ^bishopBlockerMask[square])
+			if BishopAttacks(square, occupancy) != slowSlidingAttacks(square, occupancy, bishopDirections) {
+				t.Fatalf("Invalid bishop attacks on square %s with occupancy %x", SquareString[square], occupancy)
+			}
+		}
+	}
+}
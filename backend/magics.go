@@ -0,0 +1,41 @@
+// Code generated by tools/magics; DO NOT EDIT.
+
+package backend
+
+var rookMagicIndex = [64]uint64{
+	0x0480001080224000, 0x001000F0D8400400, 0x0820080140802420, 0x0948006900100080,
+	0x0500050900180002, 0x1180068024010200, 0x0180111846000080, 0x0080030010204080,
+	0x0300800040003482, 0x0045184301080011, 0x200E000810222180, 0x2801480080410020,
+	0x8201060600100101, 0x02100C468244001A, 0x00044000C0038601, 0x0480420140008100,
+	0x2700A14010004100, 0x8344040210002500, 0x0422401080120820, 0x0504000810A21010,
+	0x0010010002082012, 0x0000802806140002, 0xE0C288400C190010, 0x8000100400200048,
+	0x044000208000409A, 0x6000400084011834, 0x000200C410021850, 0x4006840040081000,
+	0x0024401020040902, 0x8425020100098802, 0x001818812001C088, 0x0001002280008841,
+	0x00004034A0800082, 0x320440000C101040, 0x1908000400600020, 0x002C10010C880008,
+	0x200050010B080022, 0x0001906040500102, 0x084018101400008A, 0x0100090000A00048,
+	0x0010088000100402, 0x0124200410014004, 0x80080040CC024008, 0x8100102200405000,
+	0x0404004002004014, 0x8024840001204026, 0x8510820010561008, 0x0000018020194002,
+	0x0021043082120200, 0x0000428088A01100, 0x4000200004308180, 0x3000302102801001,
+	0x00402010094C4200, 0x8009100100020442, 0x048081220A400080, 0xC0640C0400A00510,
+	0x11A598A042800101, 0x00108010E2040822, 0x00C5102082001842, 0x401048C250020062,
+	0x0014050030280091, 0xC000E08904120022, 0x0810208200114401, 0x4000050440802402,
+}
+
+var bishopMagicIndex = [64]uint64{
+	0x1200831040200440, 0x0000300400200844, 0x00102C1088150000, 0x0088804040860000,
+	0x2004042900800220, 0x100DC10401100000, 0x0004140082401000, 0x090829010081A008,
+	0x0020C088001020C0, 0x0004809E504C0804, 0x0410040410500600, 0x0100500840120121,
+	0x0408020048808000, 0x000120220C400128, 0x0208000454200080, 0x0800256182208020,
+	0x0044480088840480, 0x1400842018090020, 0x2012008200801006, 0x00104002A4002030,
+	0x0004000201041000, 0x9440220200104800, 0x0108100100401010, 0x8402010008498840,
+	0x500088050C180440, 0x0000203250024180, 0x8013006200C80200, 0x8186080004014008,
+	0x4021001045004001, 0x0000111002002082, 0x0082002000080204, 0x0400A10000100A00,
+	0x840200400015F100, 0x0200344808062010, 0x00400804040040C0, 0x010C208020480200,
+	0x0080EB00400C0040, 0x0281002208249001, 0x8812008004820128, 0x800010200A000850,
+	0x0021011020890212, 0x4000820606200880, 0x0001051201208200, 0x000000200800C021,
+	0x0088060403400120, 0x0003043000101102, 0x4802008202102400, 0x0210010300190C14,
+	0x0000084520011240, 0x0000801800100400, 0x0100361680500000, 0x7012004614208080,
+	0x000A081880269400, 0x0022210A10001A20, 0x208848C102C0800C, 0x2002280110220080,
+	0x0200342414408200, 0x000002820400C100, 0x49000D0004105000, 0x0400284D208A0902,
+	0x0000048008808300, 0x2004001000984820, 0x061B168400B5018B, 0x2044082011102038,
+}
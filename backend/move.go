@@ -121,3 +121,8 @@ func (m Move) String() string {
 	}
 	return SquareString[m.From()] + SquareString[m.To()] + promo
 }
+
+// MarshalText encodes move in long algebraic notation, so that moves are readable in JSON
+func (m Move) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
@@ -236,14 +236,50 @@ func GenerateNoisy(pos *Position, buffer []EvaledMove) (size uint8) {
 
 func GenerateAllLegalMoves(pos *Position) []EvaledMove {
 	var buffer [256]EvaledMove
-	var child Position
-	noisySize := GenerateNoisy(pos, buffer[:])
-	quietsSize := GenerateQuiet(pos, buffer[noisySize:])
-	result := make([]EvaledMove, 0)
-	for _, move := range buffer[:noisySize+quietsSize] {
-		if pos.MakeMove(move.Move, &child) {
-			result = append(result, move)
+	size := GenerateLegal(pos, buffer[:])
+	result := make([]EvaledMove, size)
+	copy(result, buffer[:size])
+	return result
+}
+
+// GenerateQuietChecks generates quiet moves that give direct or discovered check.
+// Castling and promotions are not included.
+func GenerateQuietChecks(pos *Position, buffer []EvaledMove) (size uint8) {
+	sideToMove := pos.SideToMove
+	ourOccupation := pos.Colours[sideToMove]
+	theirOccupation := pos.Colours[sideToMove^1]
+	allOccupation := ourOccupation | theirOccupation
+	kingSquare := BitScan(pos.Pieces[King] & theirOccupation)
+
+	var checkSquares [King + 1]uint64
+	checkSquares[Pawn] = PawnAttacks[sideToMove^1][kingSquare]
+	checkSquares[Knight] = KnightAttacks[kingSquare]
+	checkSquares[Bishop] = BishopAttacks(kingSquare, allOccupation)
+	checkSquares[Rook] = RookAttacks(kingSquare, allOccupation)
+	checkSquares[Queen] = checkSquares[Bishop] | checkSquares[Rook]
+
+	// Our pieces that are the only blockers between our slider and their king
+	var discoverers uint64
+	snipers := ((RookAttacks(kingSquare, 0) & (pos.Pieces[Rook] | pos.Pieces[Queen])) |
+		(BishopAttacks(kingSquare, 0) & (pos.Pieces[Bishop] | pos.Pieces[Queen]))) & ourOccupation
+	for ; snipers != 0; snipers &= snipers - 1 {
+		blockers := BetweenBB[kingSquare][BitScan(snipers)] & allOccupation
+		if OnlyOne(blockers) && blockers&ourOccupation != 0 {
+			discoverers |= blockers
 		}
 	}
-	return result
+
+	quietsSize := GenerateQuiet(pos, buffer)
+	for _, move := range buffer[:quietsSize] {
+		if move.IsCastling() {
+			continue
+		}
+		from, to := move.From(), move.To()
+		if checkSquares[move.MovedPiece()]&SquareMask[to] != 0 ||
+			(discoverers&SquareMask[from] != 0 && LineBB[kingSquare][from]&SquareMask[to] == 0) {
+			buffer[size] = move
+			size++
+		}
+	}
+	return
 }
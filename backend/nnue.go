@@ -0,0 +1,127 @@
+package backend
+
+import "github.com/mhib/combusken/nnue"
+
+// Accumulators of neural network evaluation are not part of position value, as they are large.
+// Position points to accumulator owned by caller, e.g. search keeps one for every height of its stack.
+// Accumulator is updated incrementally in MakeMove when accumulator of the parent is computed, with the same network.
+// Positions that were not created by a move have their accumulators computed from scratch by evaluation.
+
+type pieceChange struct {
+	side, kind, square int
+}
+
+// RefreshAccumulator computes accumulator from scratch, position has to have an accumulator
+func (pos *Position) RefreshAccumulator(net *nnue.Network) {
+	for perspective := Black; perspective <= White; perspective++ {
+		pos.refreshPerspective(net, perspective)
+	}
+}
+
+func (pos *Position) refreshPerspective(net *nnue.Network, perspective int) {
+	pos.Accumulator.Reset(net, perspective)
+	kingSquare := BitScan(pos.Pieces[King] & pos.Colours[perspective])
+	for side := Black; side <= White; side++ {
+		for kind := Pawn; kind < King; kind++ {
+			for fromBB := pos.Pieces[kind] & pos.Colours[side]; fromBB != 0; fromBB &= fromBB - 1 {
+				pos.Accumulator.Add(net, perspective, nnue.FeatureIndex(perspective, kingSquare, side, kind, BitScan(fromBB)))
+			}
+		}
+	}
+}
+
+// updateAccumulator updates accumulator of position created by move from parent
+func (pos *Position) updateAccumulator(parent *Position, move Move) {
+	net := pos.parentNetwork(parent)
+	if net == nil {
+		if pos.Accumulator != nil {
+			pos.Accumulator.Invalidate()
+		}
+		return
+	}
+	us := parent.SideToMove
+	var added, removed [2]pieceChange
+	addedCount, removedCount := 0, 0
+	if move.IsPromotion() {
+		removed[removedCount] = pieceChange{us, Pawn, move.From()}
+		added[addedCount] = pieceChange{us, move.PromotedPiece(), move.To()}
+	} else {
+		removed[removedCount] = pieceChange{us, move.MovedPiece(), move.From()}
+		added[addedCount] = pieceChange{us, move.MovedPiece(), move.To()}
+	}
+	removedCount++
+	addedCount++
+	switch move.Type() {
+	case EPCapture:
+		removed[removedCount] = pieceChange{us ^ 1, Pawn, parent.EpSquare}
+		removedCount++
+	case KingCastle, QueenCastle:
+		rookFrom, rookTo := H1, F1
+		if move.Type() == QueenCastle {
+			rookFrom, rookTo = A1, D1
+		}
+		if us == Black {
+			rookFrom, rookTo = rookFrom^56, rookTo^56
+		}
+		removed[removedCount] = pieceChange{us, Rook, rookFrom}
+		added[addedCount] = pieceChange{us, Rook, rookTo}
+		removedCount++
+		addedCount++
+	default:
+		if move.IsCapture() {
+			removed[removedCount] = pieceChange{us ^ 1, move.CapturedPiece(), move.To()}
+			removedCount++
+		}
+	}
+
+	for perspective := Black; perspective <= White; perspective++ {
+		// King is not a feature, but features depend on its square
+		if move.MovedPiece() == King && perspective == us {
+			pos.refreshPerspective(net, perspective)
+			continue
+		}
+		kingSquare := BitScan(pos.Pieces[King] & pos.Colours[perspective])
+		var addedFeatures, removedFeatures [2]int
+		addedSize, removedSize := 0, 0
+		for _, change := range added[:addedCount] {
+			if change.kind != King {
+				addedFeatures[addedSize] = nnue.FeatureIndex(perspective, kingSquare, change.side, change.kind, change.square)
+				addedSize++
+			}
+		}
+		for _, change := range removed[:removedCount] {
+			if change.kind != King {
+				removedFeatures[removedSize] = nnue.FeatureIndex(perspective, kingSquare, change.side, change.kind, change.square)
+				removedSize++
+			}
+		}
+		pos.Accumulator.Apply(parent.Accumulator, net, perspective, addedFeatures[:addedSize], removedFeatures[:removedSize])
+	}
+}
+
+// parentNetwork returns network of parent accumulator that can be updated to accumulator of position,
+// nil if either of them is missing or they share accumulator
+func (pos *Position) parentNetwork(parent *Position) *nnue.Network {
+	if pos.Accumulator == nil || parent.Accumulator == nil || pos.Accumulator == parent.Accumulator {
+		return nil
+	}
+	return parent.Accumulator.Network()
+}
+
+// copyAccumulator copies accumulator after null move
+func (pos *Position) copyAccumulator(parent *Position) {
+	if pos.Accumulator == parent.Accumulator {
+		// Null move does not change features, so shared accumulator stays valid
+		return
+	}
+	net := pos.parentNetwork(parent)
+	if net == nil {
+		if pos.Accumulator != nil {
+			pos.Accumulator.Invalidate()
+		}
+		return
+	}
+	for perspective := Black; perspective <= White; perspective++ {
+		pos.Accumulator.Apply(parent.Accumulator, net, perspective, nil, nil)
+	}
+}
@@ -0,0 +1,90 @@
+package backend
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/mhib/combusken/nnue"
+)
+
+func compareAccumulators(t *testing.T, net *nnue.Network, pos *Position, depth int) bool {
+	var expected Position = *pos
+	expected.Accumulator = new(nnue.Accumulator)
+	expected.RefreshAccumulator(net)
+	if !pos.Accumulator.IsComputed(net) || pos.Accumulator.Values != expected.Accumulator.Values {
+		t.Errorf("Accumulator differs after %v", pos.LastMove)
+		pos.Print()
+		return false
+	}
+	if depth == 0 {
+		return true
+	}
+	var buffer [256]EvaledMove
+	child := Position{Accumulator: new(nnue.Accumulator)}
+	legalChild := Position{Accumulator: new(nnue.Accumulator)}
+	noisySize := GenerateNoisy(pos, buffer[:])
+	quietsSize := GenerateQuiet(pos, buffer[noisySize:])
+	for _, move := range buffer[:noisySize+quietsSize] {
+		if !pos.MakeMove(move.Move, &child) {
+			continue
+		}
+		pos.MakeLegalMove(move.Move, &legalChild)
+		if *legalChild.Accumulator != *child.Accumulator {
+			t.Errorf("MakeLegalMove accumulator differs after %v", move.Move)
+			return false
+		}
+		if !compareAccumulators(t, net, &child, depth-1) {
+			return false
+		}
+	}
+	if !pos.IsInCheck() {
+		pos.MakeNullMove(&child)
+		return compareAccumulators(t, net, &child, depth-1)
+	}
+	return true
+}
+
+func TestAccumulatorUpdates(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	net := nnue.NewNetwork(16, 1)
+	for i := range net.FeatureWeights {
+		net.FeatureWeights[i] = int16(rng.Intn(201) - 100)
+	}
+	for i := range net.FeatureBiases {
+		net.FeatureBiases[i] = int16(rng.Intn(201) - 100)
+	}
+	for _, fen := range legalTestFENs {
+		pos := ParseFen(fen)
+		// Child reuses accumulator that is computed, it has to be invalidated as parent has no accumulator
+		child := Position{Accumulator: new(nnue.Accumulator)}
+		child.RefreshAccumulator(net)
+		if moves := GenerateAllLegalMoves(&pos); len(moves) > 0 {
+			pos.MakeLegalMove(moves[0].Move, &child)
+			if child.Accumulator.IsComputed(net) {
+				t.Errorf("Accumulator of %s should not be computed after %v", fen, moves[0].Move)
+			}
+		}
+		pos.Accumulator = new(nnue.Accumulator)
+		pos.RefreshAccumulator(net)
+		if !compareAccumulators(t, net, &pos, 3) {
+			t.Errorf("Failed for %s", fen)
+		}
+	}
+}
+
+func TestSharedAccumulator(t *testing.T) {
+	net := nnue.NewNetwork(16, 1)
+	pos := InitialPosition
+	pos.Accumulator = new(nnue.Accumulator)
+	pos.RefreshAccumulator(net)
+	// Copy of position shares accumulator, so it cannot be updated in place
+	child := pos
+	pos.MakeLegalMove(GenerateAllLegalMoves(&pos)[0].Move, &child)
+	if child.Accumulator.IsComputed(net) {
+		t.Error("Accumulator shared with parent should be invalidated")
+	}
+	if size := unsafe.Sizeof(Position{}); size > 128 {
+		t.Errorf("Position should not hold accumulator values, its size is %d", size)
+	}
+}
@@ -1,5 +1,20 @@
 package backend
 
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/mhib/combusken/utils"
+)
+
 func Perft(pos *Position, depth int) int {
 	result := 0
 	var child Position
@@ -18,3 +33,201 @@ func Perft(pos *Position, depth int) int {
 
 	return result
 }
+
+type perftEntry struct {
+	check uint64
+	nodes uint64
+}
+
+// PerftHashTable stores subtree sizes indexed by position key and depth.
+// It can be shared by many threads as entries are validated with xor of key and value.
+type PerftHashTable struct {
+	Entries []perftEntry
+	Mask    uint64
+}
+
+func NewPerftHashTable(megabytes int) *PerftHashTable {
+	size := utils.NearestPowerOfTwo(1024 * 1024 * megabytes / int(unsafe.Sizeof(perftEntry{})))
+	return &PerftHashTable{make([]perftEntry, size), size - 1}
+}
+
+func perftKey(key uint64, depth int) uint64 {
+	return key ^ (uint64(depth) * 0x9E3779B97F4A7C15)
+}
+
+func (t *PerftHashTable) Get(key uint64, depth int) (bool, int) {
+	key = perftKey(key, depth)
+	element := &t.Entries[key&t.Mask]
+	nodes := atomic.LoadUint64(&element.nodes)
+	if atomic.LoadUint64(&element.check)^nodes != key {
+		return false, 0
+	}
+	return true, int(nodes)
+}
+
+func (t *PerftHashTable) Set(key uint64, depth, nodes int) {
+	key = perftKey(key, depth)
+	element := &t.Entries[key&t.Mask]
+	atomic.StoreUint64(&element.check, key^uint64(nodes))
+	atomic.StoreUint64(&element.nodes, uint64(nodes))
+}
+
+// HashedPerft is Perft that caches results of subtrees in table
+func HashedPerft(pos *Position, depth int, table *PerftHashTable) int {
+	return hashedPerft(context.Background(), pos, depth, table)
+}
+
+// hashedPerft returns meaningless result when ctx is cancelled, such results are not cached.
+// Shallow subtrees without table are counted by LegalPerft without checking ctx.
+func hashedPerft(ctx context.Context, pos *Position, depth int, table *PerftHashTable) int {
+	if depth <= 1 || (table == nil && depth <= 3) {
+		return LegalPerft(pos, depth)
+	}
+	if ctx.Err() != nil {
+		return 0
+	}
+	if table != nil {
+		if ok, nodes := table.Get(pos.Key, depth); ok {
+			return nodes
+		}
+	}
+	result := 0
+	var child Position
+	var buffer [256]EvaledMove
+	size := GenerateLegal(pos, buffer[:])
+	for _, move := range buffer[:size] {
+		pos.MakeLegalMove(move.Move, &child)
+		result += hashedPerft(ctx, &child, depth-1, table)
+	}
+	if table != nil && ctx.Err() == nil {
+		table.Set(pos.Key, depth, result)
+	}
+	return result
+}
+
+type DivideEntry struct {
+	Move
+	Nodes int
+}
+
+// Divide calculates perft of every legal move in position.
+// Root moves are split between threads. Error is returned when ctx is cancelled before it finishes.
+func Divide(ctx context.Context, pos *Position, depth, threads int, table *PerftHashTable) ([]DivideEntry, error) {
+	moves := GenerateAllLegalMoves(pos)
+	res := make([]DivideEntry, len(moves))
+	indexes := make(chan int, len(moves))
+	for i := range moves {
+		indexes <- i
+	}
+	close(indexes)
+	var wg sync.WaitGroup
+	for i := 0; i < utils.Max(1, threads); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var child Position
+			for idx := range indexes {
+				if ctx.Err() != nil {
+					return
+				}
+				res[idx].Move = moves[idx].Move
+				pos.MakeLegalMove(moves[idx].Move, &child)
+				if depth > 1 {
+					res[idx].Nodes = hashedPerft(ctx, &child, depth-1, table)
+				} else {
+					res[idx].Nodes = 1
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PrintDivide prints divide in format used by most of the engines and returns total nodes count.
+// Nothing is printed when ctx is cancelled before divide finishes.
+func PrintDivide(ctx context.Context, w io.Writer, pos *Position, depth, threads int, table *PerftHashTable) (int, error) {
+	start := time.Now()
+	entries, err := Divide(ctx, pos, depth, threads, table)
+	if err != nil {
+		return 0, err
+	}
+	nodes := 0
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s: %d\n", entry.Move.String(), entry.Nodes)
+		nodes += entry.Nodes
+	}
+	duration := time.Since(start)
+	fmt.Fprintf(w, "\nNodes searched: %d\n", nodes)
+	fmt.Fprintf(w, "Time: %d ms\n", duration.Milliseconds())
+	fmt.Fprintf(w, "NPS: %d\n", int64(float64(nodes)/duration.Seconds()))
+	return nodes, nil
+}
+
+type PerftSuiteEntry struct {
+	Fen    string
+	Depths map[int]int
+}
+
+// ParsePerftSuiteLine parses a record in perftsuite.epd format:
+// <fen> ;D1 <nodes> ;D2 <nodes> ...
+func ParsePerftSuiteLine(line string) (res PerftSuiteEntry, err error) {
+	parts := strings.Split(line, ";")
+	res.Fen = strings.TrimSpace(parts[0])
+	res.Depths = make(map[int]int)
+	for _, part := range parts[1:] {
+		fields := strings.Fields(part)
+		if len(fields) != 2 || len(fields[0]) < 2 || fields[0][0] != 'D' {
+			return res, fmt.Errorf("Invalid perft record %q", part)
+		}
+		depth, err := strconv.Atoi(fields[0][1:])
+		if err != nil {
+			return res, err
+		}
+		nodes, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return res, err
+		}
+		res.Depths[depth] = nodes
+	}
+	return
+}
+
+// RunPerftSuite verifies every record from reader up to maxDepth and returns number of failed checks
+func RunPerftSuite(r io.Reader, w io.Writer, maxDepth, threads int, table *PerftHashTable) (failed int, err error) {
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, err := ParsePerftSuiteLine(line)
+		if err != nil {
+			return failed, err
+		}
+		pos := ParseFen(entry.Fen)
+		for depth := 1; depth <= maxDepth; depth++ {
+			expected, ok := entry.Depths[depth]
+			if !ok {
+				continue
+			}
+			nodes := 0
+			entries, _ := Divide(context.Background(), &pos, depth, threads, table)
+			for _, divide := range entries {
+				nodes += divide.Nodes
+			}
+			status := "OK"
+			if nodes != expected {
+				status = "FAILED"
+				failed++
+			}
+			fmt.Fprintf(w, "#%d D%d %d %d %s\n", lineNumber, depth, expected, nodes, status)
+		}
+	}
+	return failed, scanner.Err()
+}
@@ -45,3 +45,38 @@ func BenchmarkPerftD6(b *testing.B) {
 		Perft(&pos, 6)
 	}
 }
+
+func BenchmarkLegalPerftD4(b *testing.B) {
+	pos := InitialPosition
+	for i := 0; i < b.N; i++ {
+		LegalPerft(&pos, 4)
+	}
+}
+
+func BenchmarkLegalPerftD5(b *testing.B) {
+	pos := InitialPosition
+	for i := 0; i < b.N; i++ {
+		LegalPerft(&pos, 5)
+	}
+}
+
+var kiwipete = ParseFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq -")
+
+func BenchmarkPseudoLegalMovesWithMakeMove(b *testing.B) {
+	var buffer [256]EvaledMove
+	var child Position
+	for i := 0; i < b.N; i++ {
+		noisySize := GenerateNoisy(&kiwipete, buffer[:])
+		quietsSize := GenerateQuiet(&kiwipete, buffer[noisySize:])
+		for _, move := range buffer[:noisySize+quietsSize] {
+			kiwipete.MakeMove(move.Move, &child)
+		}
+	}
+}
+
+func BenchmarkLegalMoves(b *testing.B) {
+	var buffer [256]EvaledMove
+	for i := 0; i < b.N; i++ {
+		GenerateLegal(&kiwipete, buffer[:])
+	}
+}
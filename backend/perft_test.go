@@ -1,6 +1,10 @@
 package backend
 
-import "testing"
+import (
+	"context"
+	"strings"
+	"testing"
+)
 
 // Taken from https://github.com/ChizhovVadim/CounterGo/blob/master/common/perft_test.go
 //https://chessprogramming.wikispaces.com/Perft+Results
@@ -50,3 +54,61 @@ func TestPerft(t *testing.T) {
 		}
 	}
 }
+
+func TestDivide(t *testing.T) {
+	var tests = []struct {
+		fen   string
+		depth int
+		nodes int
+	}{
+		{
+			fen:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq -",
+			depth: 4,
+			nodes: 4085603,
+		},
+		{
+			fen:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - -",
+			depth: 5,
+			nodes: 674624,
+		},
+	}
+	table := NewPerftHashTable(16)
+	for i, test := range tests {
+		var p = ParseFen(test.fen)
+		for _, threads := range []int{1, 4} {
+			nodes := 0
+			entries, err := Divide(context.Background(), &p, test.depth, threads, table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				nodes += entry.Nodes
+			}
+			if nodes != test.nodes {
+				t.Error(i, test, threads, nodes)
+			}
+		}
+	}
+}
+
+func TestDivideCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Divide(ctx, &InitialPosition, 7, 2, nil); err != context.Canceled {
+		t.Errorf("Expected cancelled divide, got %v", err)
+	}
+}
+
+func TestRunPerftSuite(t *testing.T) {
+	suite := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 ;D1 20 ;D2 400 ;D3 8902\n" +
+		"4k3/8/8/8/8/8/8/4K2R w K - 0 1 ;D1 15 ;D2 66 ;D3 1197\n" +
+		"4k3/8/8/8/8/8/8/4K2R w K - 0 1 ;D1 16\n"
+	var out strings.Builder
+	failed, err := RunPerftSuite(strings.NewReader(suite), &out, 3, 2, NewPerftHashTable(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 1 {
+		t.Errorf("Expected exactly one failure, got %d\n%s", failed, out.String())
+	}
+}
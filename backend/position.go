@@ -3,6 +3,8 @@ package backend
 import (
 	"fmt"
 	"strings"
+
+	"github.com/mhib/combusken/nnue"
 )
 
 const (
@@ -37,6 +39,8 @@ type Position struct {
 	FiftyMove  int
 	LastMove   Move
 	Flags      uint8
+	// Neural network accumulator owned by caller, nil if position is not updated incrementally
+	Accumulator *nnue.Accumulator
 }
 
 var InitialPosition Position = ParseFen(InitialPositionFen)
@@ -109,6 +113,7 @@ func (pos *Position) MakeNullMove(res *Position) {
 	res.FiftyMove = pos.FiftyMove + 1
 	res.LastMove = NullMove
 	res.EpSquare = 0
+	res.copyAccumulator(pos)
 }
 
 func (pos *Position) MakeMove(move Move, res *Position) bool {
@@ -173,6 +178,7 @@ func (pos *Position) MakeMove(move Move, res *Position) bool {
 	res.Key ^= zobristFlags[res.Flags]
 	res.SideToMove = pos.SideToMove ^ 1
 	res.LastMove = move
+	res.updateAccumulator(pos, move)
 	return true
 }
 
@@ -280,6 +286,7 @@ func (pos *Position) MakeLegalMove(move Move, res *Position) {
 	res.Key ^= zobristFlags[res.Flags]
 	res.SideToMove = pos.SideToMove ^ 1
 	res.LastMove = move
+	res.updateAccumulator(pos, move)
 }
 
 func (pos *Position) IsMovePseudoLegal(move Move) bool {
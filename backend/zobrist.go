@@ -55,4 +55,11 @@ func HashPosition(pos *Position) {
 
 func init() {
 	initZobrist()
+	// Cuckoo tables depend on zobrist keys and attack tables
+	initCuckoo()
+}
+
+// SideToMoveKey returns key that changes position key on every move
+func SideToMoveKey() uint64 {
+	return zobristColor
 }
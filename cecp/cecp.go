@@ -0,0 +1,482 @@
+// Package cecp implements Chess Engine Communication Protocol used by XBoard and WinBoard
+// https://www.gnu.org/software/xboard/engine-intf.html
+package cecp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/engine"
+	. "github.com/mhib/combusken/utils"
+)
+
+// Score of mate in N moves is reported as mateScore + N
+const mateScore = 100000
+
+type search struct {
+	cancel context.CancelFunc
+	// Receives best move when search finishes
+	result chan backend.Move
+	// Analysis does not play moves
+	analysis bool
+}
+
+type Protocol struct {
+	engine    *engine.Engine
+	output    io.Writer
+	outputMu  sync.Mutex
+	commands  map[string]func(args ...string)
+	positions []backend.Position
+	// Current search, nil if engine is idle
+	search *search
+	// Engine only updates position in force mode
+	force      bool
+	engineSide int
+	analyzing  bool
+	// Guarded by outputMu, as it may change during search
+	post bool
+	// Options changed by memory, cores and egtpath are applied before the next search
+	dirty bool
+	quit  bool
+	// Time control from level, st and sd in milliseconds and plies
+	movesPerSession int
+	increment       int
+	moveTime        int
+	depth           int
+	// Moves played by engine since the start of the game or time control
+	engineMoves int
+	// Clocks from time and otim in milliseconds
+	engineTime   int
+	opponentTime int
+}
+
+// New returns protocol that searches with e and writes responses to output, NewGame has to be already called on e
+func New(e *engine.Engine, output io.Writer) *Protocol {
+	p := &Protocol{
+		engine:    e,
+		output:    output,
+		positions: []backend.Position{backend.InitialPosition},
+		// Black is the default side of engine after new
+		engineSide: backend.Black,
+	}
+	e.Update = p.printThinking
+	p.commands = map[string]func(args ...string){
+		"xboard":    func(...string) {},
+		"protover":  p.protoverCommand,
+		"new":       p.newCommand,
+		"force":     p.forceCommand,
+		"go":        p.goCommand,
+		"playother": p.playOtherCommand,
+		"usermove":  p.userMoveCommand,
+		"?":         p.moveNowCommand,
+		"time":      p.timeCommand,
+		"otim":      p.otimCommand,
+		"level":     p.levelCommand,
+		"st":        p.stCommand,
+		"sd":        p.sdCommand,
+		"post":      func(...string) { p.setPost(true) },
+		"nopost":    func(...string) { p.setPost(false) },
+		"analyze":   p.analyzeCommand,
+		"exit":      p.exitCommand,
+		"setboard":  p.setBoardCommand,
+		"undo":      func(...string) { p.takeBack(1) },
+		"remove":    func(...string) { p.takeBack(2) },
+		"ping":      p.pingCommand,
+		"result":    p.resultCommand,
+		"memory":    p.memoryCommand,
+		"cores":     p.coresCommand,
+		"egtpath":   p.egtPathCommand,
+		"quit":      func(...string) { p.quit = true },
+	}
+	// Commands of features that are not supported
+	for _, name := range []string{"accepted", "rejected", "random", "hard", "easy", "computer", "name", "rating", "ics", "draw", "hint", "bk", "."} {
+		p.commands[name] = func(...string) {}
+	}
+	return p
+}
+
+// Run processes commands read from input until quit or end of input
+func (p *Protocol) Run(input io.Reader) {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(input)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	for !p.quit {
+		var results chan backend.Move
+		if p.search != nil {
+			results = p.search.result
+		}
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				p.quit = true
+				break
+			}
+			p.handle(line)
+		case move := <-results:
+			p.searchFinished(move)
+		}
+	}
+	p.stopSearch()
+}
+
+func (p *Protocol) send(format string, args ...interface{}) {
+	p.outputMu.Lock()
+	defer p.outputMu.Unlock()
+	fmt.Fprintf(p.output, format+"\n", args...)
+}
+
+func (p *Protocol) setPost(post bool) {
+	p.outputMu.Lock()
+	defer p.outputMu.Unlock()
+	p.post = post
+}
+
+func (p *Protocol) handle(line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	if cmd, ok := p.commands[fields[0]]; ok {
+		cmd(fields[1:]...)
+		return
+	}
+	// Moves are sent without usermove prefix when GUI does not accept usermove feature
+	if _, ok := p.position().MakeMoveLAN(fields[0]); ok {
+		p.userMoveCommand(fields[0])
+		return
+	}
+	p.send("Error (unknown command): %s", fields[0])
+}
+
+func (p *Protocol) position() *backend.Position {
+	return &p.positions[len(p.positions)-1]
+}
+
+func (p *Protocol) protoverCommand(...string) {
+	name, version, _ := p.engine.GetInfo()
+	p.send("feature done=0")
+	p.send("feature myname=\"%s %s\" ping=1 setboard=1 playother=1 usermove=1 san=0 time=1 draw=0 sigint=0 sigterm=0 "+
+		"reuse=1 analyze=1 colors=0 variants=\"normal\" memory=1 smp=1 egt=\"syzygy\"", name, version)
+	p.send("feature done=1")
+}
+
+func (p *Protocol) newCommand(...string) {
+	p.stopSearch()
+	p.positions = []backend.Position{backend.InitialPosition}
+	p.force = false
+	p.engineSide = backend.Black
+	p.depth = 0
+	p.engineMoves = 0
+	p.engine.NewGame()
+	p.dirty = false
+	if p.analyzing {
+		p.startSearch(true)
+	}
+}
+
+func (p *Protocol) forceCommand(...string) {
+	p.stopSearch()
+	p.force = true
+}
+
+func (p *Protocol) goCommand(...string) {
+	p.stopSearch()
+	p.force = false
+	p.engineSide = p.position().SideToMove
+	p.think()
+}
+
+func (p *Protocol) playOtherCommand(...string) {
+	p.stopSearch()
+	p.force = false
+	p.engineSide = p.position().SideToMove ^ 1
+}
+
+func (p *Protocol) userMoveCommand(args ...string) {
+	if len(args) == 0 {
+		p.send("Error (missing move): usermove")
+		return
+	}
+	next, ok := p.position().MakeMoveLAN(args[0])
+	if !ok {
+		p.send("Illegal move: %s", args[0])
+		return
+	}
+	p.stopSearch()
+	p.positions = append(p.positions, next)
+	if p.analyzing {
+		p.startSearch(true)
+	} else if !p.force && p.position().SideToMove == p.engineSide {
+		p.think()
+	}
+}
+
+// moveNowCommand stops search, its best move is played when search finishes
+func (p *Protocol) moveNowCommand(...string) {
+	if p.search != nil && !p.search.analysis {
+		p.search.cancel()
+	}
+}
+
+func parseInt(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	v, err := strconv.Atoi(args[0])
+	return v, err == nil
+}
+
+// timeCommand sets engine's clock given in centiseconds
+func (p *Protocol) timeCommand(args ...string) {
+	if v, ok := parseInt(args); ok {
+		p.engineTime = v * 10
+	}
+}
+
+func (p *Protocol) otimCommand(args ...string) {
+	if v, ok := parseInt(args); ok {
+		p.opponentTime = v * 10
+	}
+}
+
+// levelCommand parses `level MPS BASE INC`, where BASE is in minutes or minutes:seconds and INC in seconds
+func (p *Protocol) levelCommand(args ...string) {
+	if len(args) != 3 {
+		p.send("Error (invalid arguments): level")
+		return
+	}
+	mps, err := strconv.Atoi(args[0])
+	if err != nil {
+		p.send("Error (invalid arguments): level")
+		return
+	}
+	var base int
+	minutesAndSeconds := strings.SplitN(args[1], ":", 2)
+	minutes, err := strconv.Atoi(minutesAndSeconds[0])
+	base = minutes * 60000
+	if err == nil && len(minutesAndSeconds) == 2 {
+		var seconds int
+		seconds, err = strconv.Atoi(minutesAndSeconds[1])
+		base += seconds * 1000
+	}
+	increment, incErr := strconv.ParseFloat(args[2], 64)
+	if err != nil || incErr != nil {
+		p.send("Error (invalid arguments): level")
+		return
+	}
+	p.movesPerSession, p.increment, p.moveTime = mps, int(increment*1000), 0
+	p.engineMoves = 0
+	p.engineTime, p.opponentTime = base, base
+}
+
+func (p *Protocol) stCommand(args ...string) {
+	if v, ok := parseInt(args); ok {
+		p.moveTime = v * 1000
+	}
+}
+
+func (p *Protocol) sdCommand(args ...string) {
+	if v, ok := parseInt(args); ok {
+		p.depth = Min(v, engine.MAX_HEIGHT-1)
+	}
+}
+
+func (p *Protocol) analyzeCommand(...string) {
+	p.stopSearch()
+	p.analyzing = true
+	p.engine.AnalyseMode.Set(true)
+	p.startSearch(true)
+}
+
+func (p *Protocol) exitCommand(...string) {
+	if !p.analyzing {
+		return
+	}
+	p.stopSearch()
+	p.analyzing = false
+	p.engine.AnalyseMode.Set(false)
+}
+
+func (p *Protocol) setBoardCommand(args ...string) {
+	fen := strings.Join(args, " ")
+	if err := backend.ValidateFen(fen); err != nil {
+		p.send("tellusererror Illegal position: %s", err.Error())
+		return
+	}
+	p.stopSearch()
+	p.positions = []backend.Position{backend.ParseFen(fen)}
+	if p.analyzing {
+		p.startSearch(true)
+	}
+}
+
+func (p *Protocol) takeBack(plies int) {
+	if len(p.positions) <= plies {
+		p.send("Error (no moves to take back): undo")
+		return
+	}
+	p.stopSearch()
+	p.positions = p.positions[:len(p.positions)-plies]
+	if p.analyzing {
+		p.startSearch(true)
+	}
+}
+
+func (p *Protocol) pingCommand(args ...string) {
+	p.send("pong %s", strings.Join(args, " "))
+}
+
+// resultCommand ends the game, engine waits for new
+func (p *Protocol) resultCommand(...string) {
+	p.stopSearch()
+	p.force = true
+}
+
+func setClamped(option *engine.IntOption, v int) {
+	option.Set(Max(option.Min, Min(option.Max, v)))
+}
+
+// memoryCommand sets size of all hash tables in megabytes
+func (p *Protocol) memoryCommand(args ...string) {
+	if v, ok := parseInt(args); ok {
+		setClamped(&p.engine.Hash, v-p.engine.PawnHash.Val)
+		p.dirty = true
+	}
+}
+
+func (p *Protocol) coresCommand(args ...string) {
+	if v, ok := parseInt(args); ok {
+		setClamped(&p.engine.Threads, v)
+		p.dirty = true
+	}
+}
+
+func (p *Protocol) egtPathCommand(args ...string) {
+	if len(args) < 2 || args[0] != "syzygy" {
+		return
+	}
+	p.engine.SyzygyPath.Set(strings.Join(args[1:], " "))
+	p.dirty = true
+}
+
+// think starts search of move that is played when it finishes, or claims result if game is over
+func (p *Protocol) think() {
+	if !p.claimResult() {
+		p.startSearch(false)
+	}
+}
+
+// claimResult sends result and returns true if side to move is mated or stalemated
+func (p *Protocol) claimResult() bool {
+	pos := p.position()
+	if len(backend.GenerateAllLegalMoves(pos)) != 0 {
+		return false
+	}
+	if !pos.IsInCheck() {
+		p.send("1/2-1/2 {Stalemate}")
+	} else if pos.SideToMove == backend.White {
+		p.send("0-1 {Black mates}")
+	} else {
+		p.send("1-0 {White mates}")
+	}
+	return true
+}
+
+func (p *Protocol) startSearch(analysis bool) {
+	if p.dirty {
+		p.engine.NewGame()
+		p.dirty = false
+	}
+	limits := engine.LimitsType{Infinite: true}
+	if !analysis {
+		limits = p.limits()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &search{cancel: cancel, result: make(chan backend.Move, 1), analysis: analysis}
+	p.search = s
+	params := engine.SearchParams{Positions: p.positions, Limits: limits}
+	go func() {
+		defer cancel()
+		s.result <- p.engine.Search(ctx, params)
+	}()
+}
+
+// stopSearch stops current search and discards its result
+func (p *Protocol) stopSearch() {
+	if p.search == nil {
+		return
+	}
+	p.search.cancel()
+	<-p.search.result
+	p.search = nil
+}
+
+func (p *Protocol) searchFinished(move backend.Move) {
+	analysis := p.search.analysis
+	p.search = nil
+	if analysis || move == backend.NullMove {
+		return
+	}
+	next, _ := p.position().MakeMoveLAN(move.String())
+	p.positions = append(p.positions, next)
+	p.engineMoves++
+	p.send("move %s", move.String())
+	p.claimResult()
+}
+
+func (p *Protocol) limits() engine.LimitsType {
+	limits := engine.LimitsType{Depth: p.depth}
+	if p.moveTime > 0 {
+		limits.MoveTime = p.moveTime
+		return limits
+	}
+	if p.engineTime == 0 {
+		// Without time control search is limited only by depth
+		limits.Infinite = p.depth == 0
+		return limits
+	}
+	if p.position().SideToMove == backend.White {
+		limits.WhiteTime, limits.BlackTime = p.engineTime, p.opponentTime
+	} else {
+		limits.WhiteTime, limits.BlackTime = p.opponentTime, p.engineTime
+	}
+	limits.WhiteIncrement, limits.BlackIncrement = p.increment, p.increment
+	if p.movesPerSession > 0 {
+		limits.MovesToGo = p.movesPerSession - p.engineMoves%p.movesPerSession
+	}
+	return limits
+}
+
+// printThinking prints `ply score time nodes pv` line with time in centiseconds
+func (p *Protocol) printThinking(info engine.SearchInfo) {
+	score := info.Score.Centipawn
+	if info.Score.Mate > 0 {
+		score = mateScore + info.Score.Mate
+	} else if info.Score.Mate < 0 {
+		score = -mateScore + info.Score.Mate
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d %d %d %d", info.Depth, score, info.Duration/10, info.Nodes))
+	for _, move := range info.Moves {
+		sb.WriteString(" ")
+		sb.WriteString(move.String())
+	}
+	sb.WriteString("\n")
+	p.outputMu.Lock()
+	defer p.outputMu.Unlock()
+	// Analysis is always printed
+	if p.post || p.analyzing {
+		io.WriteString(p.output, sb.String())
+	}
+}
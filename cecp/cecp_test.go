@@ -0,0 +1,187 @@
+package cecp
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/engine"
+)
+
+type testSession struct {
+	t      *testing.T
+	input  *io.PipeWriter
+	lines  chan string
+	closed chan struct{}
+}
+
+func newTestSession(t *testing.T) *testSession {
+	e := engine.New()
+	e.Hash.Set(4)
+	e.NewGame()
+	inputReader, inputWriter := io.Pipe()
+	outputReader, outputWriter := io.Pipe()
+	s := &testSession{t: t, input: inputWriter, lines: make(chan string, 1024), closed: make(chan struct{})}
+	go func() {
+		New(e, outputWriter).Run(inputReader)
+		outputWriter.Close()
+		e.Close()
+		close(s.closed)
+	}()
+	go func() {
+		scanner := bufio.NewScanner(outputReader)
+		for scanner.Scan() {
+			s.lines <- scanner.Text()
+		}
+		close(s.lines)
+	}()
+	return s
+}
+
+func (s *testSession) send(commands ...string) {
+	for _, command := range commands {
+		io.WriteString(s.input, command+"\n")
+	}
+}
+
+// expect reads output until line with given prefix and returns it
+func (s *testSession) expect(prefix string) string {
+	s.t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				s.t.Fatalf("Output closed while waiting for %s", prefix)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line
+			}
+		case <-timeout:
+			s.t.Fatalf("Timeout while waiting for %s", prefix)
+		}
+	}
+}
+
+func (s *testSession) close() {
+	s.send("quit")
+	<-s.closed
+}
+
+func TestFeatures(t *testing.T) {
+	s := newTestSession(t)
+	defer s.close()
+	s.send("xboard", "protover 2")
+	features := s.expect("feature myname")
+	for _, feature := range []string{"ping=1", "setboard=1", "usermove=1", "analyze=1", "memory=1", "smp=1", `egt="syzygy"`} {
+		if !strings.Contains(features, feature) {
+			t.Errorf("Expected %s in %s", feature, features)
+		}
+	}
+	s.expect("feature done=1")
+	s.send("memory 32", "cores 1", "egtpath syzygy /nonexistent", "unknowncommand", "ping 7")
+	if line := s.expect("Error"); line != "Error (unknown command): unknowncommand" {
+		t.Errorf("Unexpected error %s", line)
+	}
+	s.expect("pong 7")
+}
+
+func TestGame(t *testing.T) {
+	s := newTestSession(t)
+	defer s.close()
+	s.send("xboard", "protover 2", "new", "sd 4", "post", "usermove e2e4")
+	if thinking := s.expect("1 "); len(strings.Fields(thinking)) < 5 {
+		t.Errorf("Expected ply, score, time, nodes and pv, got %s", thinking)
+	}
+	move := strings.TrimPrefix(s.expect("move "), "move ")
+	pos := backend.InitialPosition
+	pos, _ = pos.MakeMoveLAN("e2e4")
+	if _, ok := pos.MakeMoveLAN(move); !ok {
+		t.Errorf("Expected legal move of black, got %s", move)
+	}
+
+	s.send("usermove e2e5")
+	s.expect("Illegal move: e2e5")
+
+	// Engine plays white after go
+	s.send("force", "remove", "undo", "nopost", "go")
+	move = strings.TrimPrefix(s.expect("move "), "move ")
+	if _, ok := backend.InitialPosition.MakeMoveLAN(move); !ok {
+		t.Errorf("Expected legal move of white, got %s", move)
+	}
+
+	// Mate in one with time control
+	s.send("new", "level 40 0:30 0", "time 3000", "otim 3000", "setboard 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "go")
+	if line := s.expect("move "); line != "move a1a8" {
+		t.Errorf("Expected mate, got %s", line)
+	}
+	s.expect("1-0 {White mates}")
+	s.send("force", "setboard 6k1/5ppp/8/8/8/8/5PPP/R5K1 x", "ping 1")
+	s.expect("tellusererror Illegal position")
+	s.expect("pong 1")
+	s.send("setboard R5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 1 1", "go")
+	s.expect("1-0 {White mates}")
+}
+
+func TestAnalyze(t *testing.T) {
+	s := newTestSession(t)
+	defer s.close()
+	s.send("xboard", "new", "force", "setboard 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "analyze")
+	if line := s.expect("1 "); !strings.HasSuffix(line, "a1a8") || !strings.HasPrefix(line, "1 100001 ") {
+		t.Errorf("Expected mate in one, got %s", line)
+	}
+	// Analysis restarts after move and does not play moves
+	s.send("usermove a1a2")
+	s.expect("1 ")
+	s.send("undo", "exit", "ping 2")
+	if line := s.expect("pong"); line != "pong 2" {
+		t.Errorf("Unexpected line %s", line)
+	}
+	select {
+	case line := <-s.lines:
+		if strings.HasPrefix(line, "move") {
+			t.Errorf("Analysis should not play moves, got %s", line)
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestLimits(t *testing.T) {
+	e := engine.New()
+	defer e.Close()
+	p := New(e, ioutil.Discard)
+	p.handle("level 40 5 2.5")
+	p.handle("time 1000")
+	p.handle("otim 2000")
+	limits := p.limits()
+	if limits.WhiteTime != 10000 || limits.BlackTime != 20000 || limits.WhiteIncrement != 2500 || limits.MovesToGo != 40 {
+		t.Errorf("Unexpected limits %+v", limits)
+	}
+
+	// Only moves of engine are counted, also after setboard
+	p.handle("force")
+	for _, moves := range [][2]string{{"e2e4", "e7e5"}, {"g1f3", "b8c6"}} {
+		next, _ := p.position().MakeMoveLAN(moves[0])
+		p.search = &search{}
+		p.searchFinished(next.LastMove)
+		p.handle("usermove " + moves[1])
+	}
+	p.handle("setboard 4k3/8/8/8/8/8/8/4K2R w K - 0 1")
+	if limits = p.limits(); limits.MovesToGo != 38 {
+		t.Errorf("Expected 38 moves to go, got %d", limits.MovesToGo)
+	}
+
+	p.handle("level 0 2:30 0")
+	if p.engineTime != 150000 || p.movesPerSession != 0 {
+		t.Errorf("Expected 2:30 base time, got %d", p.engineTime)
+	}
+	p.handle("st 3")
+	p.handle("sd 7")
+	if limits = p.limits(); limits.MoveTime != 3000 || limits.Depth != 7 {
+		t.Errorf("Unexpected limits %+v", limits)
+	}
+}
@@ -1,9 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/cecp"
+	"github.com/mhib/combusken/dataset"
 	"github.com/mhib/combusken/engine"
+	"github.com/mhib/combusken/server"
+	"github.com/mhib/combusken/tablebase"
+	"github.com/mhib/combusken/training"
 	"github.com/mhib/combusken/tuning"
 	"github.com/mhib/combusken/uci"
 )
@@ -12,14 +26,272 @@ func main() {
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
 		case "tune":
-			tuning.Tune()
+			tuning.Tune(optionalArg(os.Args[2:]))
 		case "trace-tune":
-			tuning.TraceTune()
+			tuning.TraceTune(optionalArg(os.Args[2:]))
+		case "fit-wdl":
+			tuning.FitWDL()
+		case "perft":
+			err := perft(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		case "perftsuite":
+			err := perftSuite(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		case "tbgen":
+			err := tablebaseGenerate(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		case "train":
+			err := train(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		case "pack", "unpack":
+			err := convertData(os.Args[1], os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		case "calibrate":
+			err := calibrate(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		case "analyze":
+			err := analyze(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		case "-uci":
+			runUci(os.Stdin)
+		case "-xboard":
+			runCecp(os.Stdin)
+		case "serve":
+			err := serve(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
 		case "bench":
-			engine.Benchmark()
+			options, err := engine.ParseBenchmarkArgs(os.Args[2:])
+			if err == nil {
+				_, err = engine.Benchmark(options)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		case "scaling":
+			options, err := engine.ParseScalingArgs(os.Args[2:])
+			if err == nil {
+				err = engine.ScalingBenchmark(options, os.Stdout)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
 		}
 		return
 	}
+	runDetectedProtocol(os.Stdin)
+}
+
+// runDetectedProtocol runs CECP if the first command is xboard and UCI otherwise
+func runDetectedProtocol(input io.Reader) {
+	reader := bufio.NewReader(input)
+	firstLine, _ := reader.ReadString('\n')
+	input = io.MultiReader(strings.NewReader(firstLine), reader)
+	if strings.TrimSpace(firstLine) == "xboard" {
+		runCecp(input)
+	} else {
+		runUci(input)
+	}
+}
+
+func runUci(input io.Reader) {
 	uci := uci.NewUciProtocol(engine.NewEngine())
-	uci.Run()
+	uci.Run(input)
+}
+
+func runCecp(input io.Reader) {
+	e := engine.New()
+	defer e.Close()
+	cecp.New(e, os.Stdout).Run(input)
+}
+
+func optionalArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// parseIntArgs parses leading integer arguments into values and returns rest of the arguments
+func parseIntArgs(args []string, values ...*int) ([]string, error) {
+	for _, value := range values {
+		if len(args) == 0 {
+			break
+		}
+		v, err := strconv.Atoi(args[0])
+		if err != nil {
+			break
+		}
+		if v < 0 {
+			return args, errors.New("Invalid argument " + args[0])
+		}
+		*value = v
+		args = args[1:]
+	}
+	return args, nil
+}
+
+func newPerftHashTable(megabytes int) *backend.PerftHashTable {
+	if megabytes == 0 {
+		return nil
+	}
+	return backend.NewPerftHashTable(megabytes)
+}
+
+// combusken perft <depth> [threads] [hash] [fen]
+func perft(args []string) error {
+	if len(args) == 0 {
+		return errors.New("Usage: combusken perft <depth> [threads] [hash] [fen]")
+	}
+	depth, threads, hash := 0, 1, 0
+	args, err := parseIntArgs(args, &depth, &threads, &hash)
+	if err != nil {
+		return err
+	}
+	if depth < 1 {
+		return errors.New("Invalid perft depth")
+	}
+	pos := backend.InitialPosition
+	if len(args) > 0 {
+		pos = backend.ParseFen(strings.Join(args, " "))
+	}
+	_, err = backend.PrintDivide(context.Background(), os.Stdout, &pos, depth, threads, newPerftHashTable(hash))
+	return err
+}
+
+// combusken perftsuite <file> [max depth] [threads] [hash]
+func perftSuite(args []string) error {
+	if len(args) == 0 {
+		return errors.New("Usage: combusken perftsuite <file> [max depth] [threads] [hash]")
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	maxDepth, threads, hash := 6, 1, 0
+	if _, err = parseIntArgs(args[1:], &maxDepth, &threads, &hash); err != nil {
+		return err
+	}
+	failed, err := backend.RunPerftSuite(file, os.Stdout, maxDepth, threads, newPerftHashTable(hash))
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d perft checks failed", failed)
+	}
+	return nil
+}
+
+// combusken tbgen <dir> [pieces]
+func tablebaseGenerate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("Usage: combusken tbgen <dir> [pieces]")
+	}
+	pieces := 4
+	if _, err := parseIntArgs(args[1:], &pieces); err != nil {
+		return err
+	}
+	if pieces < 3 || pieces > 4 {
+		return errors.New("Tables can be generated for 3 or 4 pieces")
+	}
+	if err := os.MkdirAll(args[0], 0755); err != nil {
+		return err
+	}
+	return tablebase.Generate(args[0], pieces, os.Stdout)
+}
+
+// combusken train <data> <network> [epochs] [half dimensions] [hidden dimensions]
+func train(args []string) error {
+	if len(args) < 2 {
+		return errors.New("Usage: combusken train <data> <network> [epochs] [half dimensions] [hidden dimensions]")
+	}
+	options := training.DefaultOptions()
+	options.DataPath = args[0]
+	options.NetworkPath = args[1]
+	options.CheckpointPath = args[1] + ".checkpoint"
+	if _, err := parseIntArgs(args[2:], &options.Epochs, &options.HalfDimensions, &options.HiddenDimensions); err != nil {
+		return err
+	}
+	return training.Train(options)
+}
+
+// combusken pack <text input> <binary output>
+// combusken unpack <binary input> <text output>
+func convertData(command string, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Usage: combusken " + command + " <input> <output>")
+	}
+	count, err := dataset.Convert(args[0], args[1], command == "unpack")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Converted %d positions\n", count)
+	return nil
+}
+
+// combusken calibrate [games] [move time] [anchor elo] [level step]
+func calibrate(args []string) error {
+	options := engine.DefaultCalibrationOptions()
+	if _, err := parseIntArgs(args, &options.Games, &options.MoveTime, &options.AnchorElo, &options.LevelStep); err != nil {
+		return err
+	}
+	if options.Games < 1 || options.MoveTime < 1 || options.LevelStep < 1 || options.LevelStep > engine.MaxSkillLevel {
+		return errors.New("Usage: combusken calibrate [games] [move time] [anchor elo] [level step]")
+	}
+	engine.Calibrate(options, os.Stdout)
+	return nil
+}
+
+// combusken analyze [depth] [nodes] [move time] [workers] [hash]
+func analyze(args []string) error {
+	options := engine.DefaultBatchOptions()
+	limits := &options.Limits
+	if _, err := parseIntArgs(args, &limits.Depth, &limits.Nodes, &limits.MoveTime, &options.Workers, &options.Hash); err != nil {
+		return err
+	}
+	if limits.Depth == 0 && limits.Nodes == 0 && limits.MoveTime == 0 || limits.Depth >= engine.MAX_HEIGHT || options.Workers < 1 || options.Hash < 1 {
+		return errors.New("Usage: combusken analyze [depth] [nodes] [move time] [workers] [hash]")
+	}
+	return engine.AnalyseBatch(context.Background(), os.Stdin, os.Stdout, options)
+}
+
+// combusken serve [address]
+func serve(args []string) error {
+	address := "localhost:8080"
+	if len(args) > 0 {
+		address = args[0]
+	}
+	e := engine.New()
+	defer e.Close()
+	if info := e.EvaluationInfo(); info != "" {
+		fmt.Println(info)
+	}
+	fmt.Println("Listening on " + address)
+	return http.ListenAndServe(address, server.New(e))
 }
@@ -0,0 +1,162 @@
+package dataset
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mhib/combusken/backend"
+)
+
+var testLines = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1;1/2-1/2",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w Kq - 3 1;1-0;35",
+	"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1;0-1;-120",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 99 1;1/2-1/2;0",
+	"4k3/8/8/8/8/8/8/4K2Q b - - 0 1;1-0;32767",
+}
+
+func readAll(t *testing.T, data []byte) (res []Record) {
+	reader, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, record)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var records []Record
+	for _, line := range testLines {
+		record, ok := ParseLine(line)
+		if !ok {
+			t.Fatalf("Could not parse %s", line)
+		}
+		if record.Line() != line {
+			t.Errorf("Expected %s, got %s", line, record.Line())
+		}
+		records = append(records, record)
+	}
+
+	for _, text := range []bool{false, true} {
+		var buffer bytes.Buffer
+		writer := NewWriter(&buffer)
+		if text {
+			writer = NewTextWriter(&buffer)
+		}
+		for i := range records {
+			if err := writer.Write(&records[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if !text && buffer.Len() != len(fileMagic)+1+len(records)*RecordSize {
+			t.Errorf("Unexpected binary size %d", buffer.Len())
+		}
+		res := readAll(t, buffer.Bytes())
+		if len(res) != len(records) {
+			t.Fatalf("Expected %d records, got %d", len(records), len(res))
+		}
+		for i := range res {
+			if res[i] != records[i] {
+				t.Errorf("Record %s differs after reading, text: %v", testLines[i], text)
+			}
+		}
+	}
+}
+
+func TestInvalidData(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte(fileMagic + "\x02"))); err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+	record, _ := ParseLine(testLines[0])
+	var buffer bytes.Buffer
+	writer := NewWriter(&buffer)
+	writer.Write(&record)
+	writer.Flush()
+	reader, err := NewReader(bytes.NewReader(buffer.Bytes()[:buffer.Len()-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reader.Read(); err == nil || err == io.EOF {
+		t.Error("Expected error for truncated record")
+	}
+	// Records with corrupted byte at given offset
+	header := len(fileMagic) + 1
+	for _, test := range []struct {
+		name   string
+		offset int
+		value  func(byte) byte
+	}{
+		{"two white kings", 8, func(b byte) byte { return b&0xF0 | 8 | backend.King }},
+		{"en passant square out of board", 25, func(byte) byte { return 200 }},
+		{"en passant square on wrong rank", 25, func(byte) byte { return backend.E4 }},
+		{"fifty move counter out of range", 26, func(byte) byte { return 101 }},
+	} {
+		data := append([]byte{}, buffer.Bytes()...)
+		data[header+test.offset] = test.value(data[header+test.offset])
+		reader, _ = NewReader(bytes.NewReader(data))
+		if _, err = reader.Read(); err == nil {
+			t.Errorf("Expected error for record with %s", test.name)
+		}
+	}
+	if res := readAll(t, []byte("invalid\n"+testLines[0]+"\n\n")); len(res) != 1 {
+		t.Errorf("Expected invalid lines to be skipped, got %d records", len(res))
+	}
+}
+
+func TestConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dataset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var text []byte
+	for _, line := range testLines {
+		text = append(text, line+"\n"...)
+	}
+	textPath := filepath.Join(dir, "games.fen")
+	if err = ioutil.WriteFile(textPath, text, 0644); err != nil {
+		t.Fatal(err)
+	}
+	binaryPath := filepath.Join(dir, "games.bin")
+	if count, err := Convert(textPath, binaryPath, false); err != nil || count != len(testLines) {
+		t.Fatalf("Packing failed: %d %v", count, err)
+	}
+	for _, path := range []string{textPath, binaryPath} {
+		var mu sync.Mutex
+		lines := make(map[string]bool)
+		err := ReadFileParallel(path, 3, func(r *Record) {
+			mu.Lock()
+			lines[r.Line()] = true
+			mu.Unlock()
+		})
+		if err != nil || len(lines) != len(testLines) {
+			t.Errorf("%s: expected %d records read in parallel, got %d %v", path, len(testLines), len(lines), err)
+		}
+	}
+	resultPath := filepath.Join(dir, "result.fen")
+	if count, err := Convert(binaryPath, resultPath, true); err != nil || count != len(testLines) {
+		t.Fatalf("Unpacking failed: %d %v", count, err)
+	}
+	result, err := ioutil.ReadFile(resultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(result, text) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", text, result)
+	}
+}
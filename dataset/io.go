@@ -0,0 +1,206 @@
+package dataset
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// Binary files start with magic and version followed by records
+const fileMagic = "CBPR"
+const fileVersion = 1
+
+type Writer struct {
+	writer *bufio.Writer
+	text   bool
+	buffer [RecordSize]byte
+	header bool
+}
+
+// NewWriter creates writer of binary records
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{writer: bufio.NewWriter(w)}
+}
+
+// NewTextWriter creates writer of text lines
+func NewTextWriter(w io.Writer) *Writer {
+	return &Writer{writer: bufio.NewWriter(w), text: true}
+}
+
+func (w *Writer) Write(r *Record) error {
+	if w.text {
+		_, err := w.writer.WriteString(r.Line() + "\n")
+		return err
+	}
+	if !w.header {
+		w.header = true
+		w.writer.WriteString(fileMagic)
+		w.writer.WriteByte(fileVersion)
+	}
+	r.pack(w.buffer[:])
+	_, err := w.writer.Write(w.buffer[:])
+	return err
+}
+
+// Flush writes buffered data, it must be called after the last record
+func (w *Writer) Flush() error {
+	if !w.text && !w.header {
+		// Empty binary file still has a header
+		w.header = true
+		w.writer.WriteString(fileMagic)
+		w.writer.WriteByte(fileVersion)
+	}
+	return w.writer.Flush()
+}
+
+// Reader reads records from binary or text data, format is detected from the header.
+// Text lines that cannot be parsed are skipped.
+type Reader struct {
+	reader  *bufio.Reader
+	scanner *bufio.Scanner
+	buffer  [RecordSize]byte
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	res := &Reader{reader: bufio.NewReader(r)}
+	header, err := res.reader.Peek(len(fileMagic) + 1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.HasPrefix(header, []byte(fileMagic)) {
+		if len(header) <= len(fileMagic) || header[len(fileMagic)] != fileVersion {
+			return nil, errors.New("Unsupported data file version")
+		}
+		res.reader.Discard(len(header))
+	} else {
+		res.scanner = bufio.NewScanner(res.reader)
+	}
+	return res, nil
+}
+
+// IsText returns true if data is in text format
+func (r *Reader) IsText() bool {
+	return r.scanner != nil
+}
+
+// Read returns next record or io.EOF after the last one
+func (r *Reader) Read() (Record, error) {
+	if r.scanner != nil {
+		for r.scanner.Scan() {
+			if res, ok := ParseLine(r.scanner.Text()); ok {
+				return res, nil
+			}
+		}
+		if err := r.scanner.Err(); err != nil {
+			return Record{}, err
+		}
+		return Record{}, io.EOF
+	}
+	if _, err := io.ReadFull(r.reader, r.buffer[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Record{}, errors.New("Truncated record")
+		}
+		return Record{}, err
+	}
+	var res Record
+	err := res.unpack(r.buffer[:])
+	return res, err
+}
+
+// ReadFile calls fn with every record of a file
+func ReadFile(path string, fn func(*Record)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader, err := NewReader(file)
+	if err != nil {
+		return err
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		fn(&record)
+	}
+}
+
+// ReadFileParallel calls fn with every record of a file, fn may be called concurrently.
+// Lines of text files are parsed by workers goroutines, as parsing is much slower than reading.
+func ReadFileParallel(path string, workers int, fn func(*Record)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader, err := NewReader(file)
+	if err != nil {
+		return err
+	}
+	if !reader.IsText() {
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			fn(&record)
+		}
+	}
+	lines := make(chan string, workers*64)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range lines {
+				if record, ok := ParseLine(line); ok {
+					fn(&record)
+				}
+			}
+		}()
+	}
+	for reader.scanner.Scan() {
+		lines <- reader.scanner.Text()
+	}
+	close(lines)
+	wg.Wait()
+	return reader.scanner.Err()
+}
+
+// Convert copies records from input file to output file in binary or text format
+func Convert(inputPath, outputPath string, text bool) (count int, err error) {
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return 0, err
+	}
+	defer output.Close()
+	writer := NewWriter(output)
+	if text {
+		writer = NewTextWriter(output)
+	}
+	var writeErr error
+	err = ReadFile(inputPath, func(r *Record) {
+		if writeErr == nil {
+			writeErr = writer.Write(r)
+			count++
+		}
+	})
+	if err != nil {
+		return count, err
+	} else if writeErr != nil {
+		return count, writeErr
+	}
+	if err = writer.Flush(); err != nil {
+		return count, err
+	}
+	return count, output.Close()
+}
@@ -0,0 +1,164 @@
+// Package dataset reads and writes positions used for tuning and training.
+// Positions are stored either as text lines in "fen;result" or "fen;result;score" format
+// or as packed binary records that are several times smaller and do not need FEN parsing.
+package dataset
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+
+	. "github.com/mhib/combusken/backend"
+	. "github.com/mhib/combusken/utils"
+)
+
+// Game results from white perspective
+const (
+	BlackWin = iota
+	Draw
+	WhiteWin
+)
+
+// NoScore marks record without search score
+const NoScore = -32768
+
+type Record struct {
+	Position Position
+	Result   int
+	// Search score in centipawns from white perspective
+	Score int
+}
+
+// WhiteResult returns game result from white perspective as 0, 0.5 or 1
+func (r *Record) WhiteResult() float64 {
+	return float64(r.Result) / 2
+}
+
+// Record layout:
+// occupancy bitboard(8 bytes), pieces of occupied squares from A1 to H8 packed in nibbles(16 bytes),
+// castling flags and side to move(1 byte), en passant square(1 byte), fifty move counter(1 byte),
+// result(1 byte) and score(2 bytes). Multi byte values are little endian.
+const RecordSize = 30
+
+const maxPieces = 32
+
+// Game is drawn by fifty move rule after 100 plies without capture or pawn move
+const maxFiftyMove = 100
+
+func (r *Record) pack(buffer []byte) {
+	pos := &r.Position
+	occupancy := pos.Colours[White] | pos.Colours[Black]
+	binary.LittleEndian.PutUint64(buffer, occupancy)
+	pieces := buffer[8:24]
+	for i := range pieces {
+		pieces[i] = 0
+	}
+	for i, bb := 0, occupancy; bb != 0; i, bb = i+1, bb&(bb-1) {
+		bit := bb & -bb
+		var nibble byte
+		for kind := Pawn; kind <= King; kind++ {
+			if pos.Pieces[kind]&bit != 0 {
+				nibble = byte(kind)
+			}
+		}
+		if pos.Colours[White]&bit != 0 {
+			nibble |= 8
+		}
+		pieces[i/2] |= nibble << uint(4*(i&1))
+	}
+	buffer[24] = pos.Flags&0xF | byte(pos.SideToMove)<<4
+	buffer[25] = byte(pos.EpSquare)
+	buffer[26] = byte(Min(pos.FiftyMove, 255))
+	buffer[27] = byte(r.Result)
+	score := r.Score
+	if score != NoScore {
+		score = Max(Min(score, 32767), -32767)
+	}
+	binary.LittleEndian.PutUint16(buffer[28:], uint16(int16(score)))
+}
+
+func (r *Record) unpack(buffer []byte) error {
+	var pos Position
+	occupancy := binary.LittleEndian.Uint64(buffer)
+	if PopCount(occupancy) > maxPieces || buffer[27] > WhiteWin || buffer[24]>>4 > White {
+		return errors.New("Invalid record")
+	}
+	for i, bb := 0, occupancy; bb != 0; i, bb = i+1, bb&(bb-1) {
+		bit := bb & -bb
+		nibble := buffer[8+i/2] >> uint(4*(i&1)) & 0xF
+		if int(nibble&7) > King {
+			return errors.New("Invalid record")
+		}
+		pos.Pieces[nibble&7] |= bit
+		pos.Colours[nibble>>3] |= bit
+	}
+	if PopCount(pos.Pieces[King]&pos.Colours[White]) != 1 || PopCount(pos.Pieces[King]&pos.Colours[Black]) != 1 {
+		return errors.New("Invalid record")
+	}
+	pos.Flags = buffer[24] & 0xF
+	pos.SideToMove = int(buffer[24] >> 4)
+	pos.EpSquare = int(buffer[25])
+	pos.FiftyMove = int(buffer[26])
+	if !validEpSquare(&pos) || pos.FiftyMove > maxFiftyMove {
+		return errors.New("Invalid record")
+	}
+	HashPosition(&pos)
+	r.Position = pos
+	r.Result = int(buffer[27])
+	r.Score = int(int16(binary.LittleEndian.Uint16(buffer[28:])))
+	return nil
+}
+
+// ParseLine parses line in "fen;result" or "fen;result;score" format.
+// Result is "1-0", "0-1" or anything else for a draw.
+func ParseLine(line string) (Record, bool) {
+	fields := strings.Split(line, ";")
+	if len(fields) < 2 {
+		return Record{}, false
+	}
+	res := Record{Result: Draw, Score: NoScore}
+	if strings.Contains(fields[1], "1-0") {
+		res.Result = WhiteWin
+	} else if strings.Contains(fields[1], "0-1") {
+		res.Result = BlackWin
+	}
+	if len(fields) > 2 {
+		if score, err := strconv.Atoi(strings.TrimSpace(fields[2])); err == nil {
+			res.Score = score
+		}
+	}
+	if len(strings.Fields(fields[0])) < 2 {
+		return Record{}, false
+	}
+	res.Position = ParseFen(strings.TrimSpace(fields[0]))
+	if PopCount(res.Position.Pieces[King]&res.Position.Colours[White]) != 1 ||
+		PopCount(res.Position.Pieces[King]&res.Position.Colours[Black]) != 1 ||
+		!validEpSquare(&res.Position) || res.Position.FiftyMove > maxFiftyMove {
+		return Record{}, false
+	}
+	return res, true
+}
+
+// validEpSquare returns true if there is no en passant square
+// or it holds square of pawn that has just been pushed by two squares by the side not to move
+func validEpSquare(pos *Position) bool {
+	if pos.EpSquare == 0 {
+		return true
+	}
+	if pos.SideToMove == White {
+		return pos.EpSquare >= A5 && pos.EpSquare <= H5
+	}
+	return pos.EpSquare >= A4 && pos.EpSquare <= H4
+}
+
+var resultStrings = [...]string{"0-1", "1/2-1/2", "1-0"}
+
+// Line returns record in text format
+func (r *Record) Line() string {
+	res := r.Position.Fen() + ";" + resultStrings[r.Result]
+	if r.Score != NoScore {
+		res += ";" + strconv.Itoa(r.Score)
+	}
+	return res
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mhib/combusken/backend"
+)
+
+// Bound tells whether reported score is exact or only a bound of the real one
+type Bound int
+
+const (
+	BoundExact Bound = iota
+	// Real score is at least reported one
+	BoundLower
+	// Real score is at most reported one
+	BoundUpper
+)
+
+// Results of failed aspiration searches are reported only in long searches
+const boundReportDelay = 3 * time.Second
+
+// SearchResult describes outcome of a search
+type SearchResult struct {
+	BestMove backend.Move
+	// Expected reply to the best move, NullMove if it is unknown
+	PonderMove backend.Move
+	Score      UciScore
+	Bound      Bound
+	Depth      int
+	SelDepth   int
+	Nodes      int
+	PV         []backend.Move
+	Time       time.Duration
+	// Zero unless UCI_ShowWDL is set
+	WDL WDLScore
+}
+
+// New returns engine with default options that is ready to search.
+// Options are applied by NewGame, so it has to be called after they are changed.
+func New() *Engine {
+	e := NewEngine()
+	e.NewGame()
+	return &e
+}
+
+// Close stops goroutines of search threads, engine can be used again after NewGame
+func (e *Engine) Close() {
+	e.closeThreads()
+}
+
+// Analyse searches the last of given positions and returns the best move with details of search.
+// Search stops when limits are reached or ctx is done.
+func (e *Engine) Analyse(ctx context.Context, searchParams SearchParams) SearchResult {
+	e.fillMoveHistory(searchParams.Positions)
+	e.timeManager = newTimeManager(searchParams.Limits, e.MoveOverhead.Val, searchParams.Positions[len(searchParams.Positions)-1].SideToMove)
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	if e.hardTimeout() > 0 {
+		ctx, cancel = context.WithTimeout(ctx, e.hardTimeout())
+	}
+	defer cancel()
+	e.done = ctx.Done()
+	e.nodeLimit = searchParams.Limits.Nodes
+	atomic.StoreInt32(&e.stop, 0)
+	res := e.bestMove(&searchParams.Positions[len(searchParams.Positions)-1])
+	info := e.searchInfo(res)
+	searchResult := SearchResult{
+		BestMove: res.Move,
+		Score:    info.Score,
+		Bound:    info.Bound,
+		Depth:    info.Depth,
+		SelDepth: info.SelDepth,
+		Nodes:    info.Nodes,
+		PV:       info.Moves,
+		Time:     e.getElapsedTime(),
+		WDL:      info.WDL,
+	}
+	if len(res.moves) > 1 {
+		searchResult.PonderMove = res.moves[1]
+	}
+	return searchResult
+}
+
+// Subscribe returns channel that receives progress of searches until unsubscribe is called.
+// Events are dropped when buffer of channel is full, so that subscribers do not slow down search.
+func (e *Engine) Subscribe(buffer int) (events <-chan SearchInfo, unsubscribe func()) {
+	ch := make(chan SearchInfo, buffer)
+	e.events.Lock()
+	e.events.subscribers = append(e.events.subscribers, ch)
+	e.events.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			e.events.Lock()
+			defer e.events.Unlock()
+			for i := range e.events.subscribers {
+				if e.events.subscribers[i] == ch {
+					e.events.subscribers = append(e.events.subscribers[:i], e.events.subscribers[i+1:]...)
+					break
+				}
+			}
+			close(ch)
+		})
+	}
+}
+
+type eventBus struct {
+	sync.Mutex
+	subscribers []chan SearchInfo
+}
+
+// publish delivers progress of search to Update and to subscribers
+func (e *Engine) publish(info SearchInfo) {
+	e.events.Lock()
+	defer e.events.Unlock()
+	e.Update(info)
+	for _, ch := range e.events.subscribers {
+		select {
+		case ch <- info:
+		default:
+		}
+	}
+}
+
+func (e *Engine) searchInfo(res result) SearchInfo {
+	nodes := e.nodes()
+	timeSinceStart := e.getElapsedTime()
+	return SearchInfo{
+		Score:    newUciScore(e.reportedScore(res.value)),
+		Bound:    res.bound,
+		Depth:    res.depth,
+		SelDepth: res.selDepth,
+		Nodes:    nodes,
+		Nps:      int(float64(nodes) / timeSinceStart.Seconds()),
+		Duration: int(timeSinceStart.Milliseconds()),
+		Moves:    res.moves,
+		WDL:      e.wdlScore(res.value),
+	}
+}
+
+func (e *Engine) reportResult(res result) {
+	e.publish(e.searchInfo(res))
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+
+	. "github.com/mhib/combusken/backend"
+	. "github.com/mhib/combusken/utils"
+)
+
+// BatchOptions configures analysis of many positions
+type BatchOptions struct {
+	// Number of single threaded engines searching positions in parallel
+	Workers int
+	Hash    int
+	// Limits of search of every position
+	Limits LimitsType
+}
+
+func DefaultBatchOptions() BatchOptions {
+	return BatchOptions{Workers: runtime.NumCPU(), Hash: 16, Limits: LimitsType{Depth: 12}}
+}
+
+// BatchResult is a JSON record written for every analysed position
+type BatchResult struct {
+	Fen      string    `json:"fen"`
+	Error    string    `json:"error,omitempty"`
+	BestMove Move      `json:"bestmove,omitempty"`
+	Score    *UciScore `json:"score,omitempty"`
+	Depth    int       `json:"depth,omitempty"`
+	Nodes    int       `json:"nodes,omitempty"`
+	// Search time in milliseconds
+	Time int    `json:"time,omitempty"`
+	PV   []Move `json:"pv,omitempty"`
+}
+
+type batchJob struct {
+	index int
+	fen   string
+}
+
+type batchRecord struct {
+	index int
+	BatchResult
+}
+
+// Results of that many positions per worker may wait for a slower position before them
+const batchWindow = 64
+
+// AnalyseBatch searches FEN or EPD records read from r and writes JSON lines with results to w in input order.
+// Empty lines and lines starting with # are skipped.
+// Every position is searched independently of others, so results do not depend on number of workers.
+func AnalyseBatch(ctx context.Context, r io.Reader, w io.Writer, options BatchOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	workers := Max(1, options.Workers)
+	jobs := make(chan batchJob)
+	records := make(chan batchRecord)
+	// Reader does not get further than window ahead of writer
+	window := make(chan struct{}, workers*batchWindow)
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(r)
+		for index := 0; scanner.Scan(); {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			jobs <- batchJob{index, fenFromEPD(line)}
+			index++
+		}
+		readErr = scanner.Err()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e := newBatchEngine(options)
+			defer e.Close()
+			for job := range jobs {
+				records <- batchRecord{job.index, e.analysePosition(ctx, job.fen, options.Limits)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(records)
+	}()
+
+	encoder := json.NewEncoder(w)
+	pending := make(map[int]BatchResult)
+	next := 0
+	var writeErr error
+	for record := range records {
+		pending[record.index] = record.BatchResult
+		for res, ok := pending[next]; ok; res, ok = pending[next] {
+			delete(pending, next)
+			next++
+			<-window
+			if writeErr == nil {
+				if writeErr = encoder.Encode(res); writeErr != nil {
+					// Remaining positions are searched only to let goroutines finish
+					cancel()
+				}
+			}
+		}
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if readErr != nil {
+		return readErr
+	}
+	return ctx.Err()
+}
+
+func newBatchEngine(options BatchOptions) *Engine {
+	e := NewEngine()
+	e.Threads.Val = 1
+	e.Hash.Val = options.Hash
+	e.NewGame()
+	return &e
+}
+
+// analysePosition searches position with tables cleared, so that result does not depend on previous positions
+func (e *Engine) analysePosition(ctx context.Context, fen string, limits LimitsType) BatchResult {
+	res := BatchResult{Fen: fen}
+	if err := ValidateFen(fen); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	pos := ParseFen(fen)
+	if len(GenerateAllLegalMoves(&pos)) == 0 {
+		res.Error = "No legal moves"
+		return res
+	}
+	e.transTable.Clear()
+	e.pawnKingTable.Clear()
+	for i := range e.threads {
+		e.threads[i].MoveHistory = MoveHistory{}
+	}
+	searchResult := e.Analyse(ctx, SearchParams{Positions: []Position{pos}, Limits: limits})
+	res.BestMove = searchResult.BestMove
+	res.Score = &searchResult.Score
+	res.Depth = searchResult.Depth
+	res.Nodes = searchResult.Nodes
+	res.Time = int(searchResult.Time.Milliseconds())
+	res.PV = searchResult.PV
+	return res
+}
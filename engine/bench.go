@@ -3,65 +3,156 @@ package engine
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	. "github.com/mhib/combusken/backend"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 )
 
-type positionTest struct {
-	Position
-	bestMoves []string
-	id        string
+// fenFromEPD extracts FEN part of a line that may be either FEN or EPD record.
+// Halfmove clock and fullmove number are kept only when they are present.
+func fenFromEPD(line string) string {
+	fields := strings.Fields(strings.SplitN(line, ";", 2)[0])
+	if len(fields) < 4 {
+		return strings.Join(fields, " ")
+	}
+	res := fields[:4]
+	for i := 4; i < len(fields) && i < 6; i++ {
+		if _, err := strconv.Atoi(fields[i]); err != nil {
+			break
+		}
+		res = append(res, fields[i])
+	}
+	return strings.Join(res, " ")
 }
 
-func loadEPD(fileLocation string) (res []positionTest) {
+func loadBenchPositions(fileLocation string) (res []string, err error) {
 	path, _ := filepath.Abs(fileLocation)
 	file, err := os.Open(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
-		var entry positionTest
-		positionAndRest := strings.Split(line, " bm ")
-		entry.Position = ParseFen(positionAndRest[0])
-		bestMovesAndID := strings.Split(positionAndRest[1], ";")
-		entry.bestMoves = strings.Split(bestMovesAndID[0], " ")
-		entry.id = bestMovesAndID[1]
-		res = append(res, entry)
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		res = append(res, fenFromEPD(line))
+	}
+	return res, scanner.Err()
+}
+
+type BenchmarkOptions struct {
+	Depth         int
+	Threads       int
+	Hash          int
+	PositionsFile string
+}
+
+func DefaultBenchmarkOptions() BenchmarkOptions {
+	return BenchmarkOptions{Depth: 5, Threads: 1, Hash: 256}
+}
+
+// DefaultScalingOptions searches deeper, as helper threads barely help in shallow searches
+func DefaultScalingOptions() BenchmarkOptions {
+	return BenchmarkOptions{Depth: 10, Threads: runtime.NumCPU(), Hash: 256}
+}
+
+// ParseBenchmarkArgs parses `bench [depth] [threads] [hash] [positions file]` arguments.
+// Missing arguments are replaced with default ones.
+func ParseBenchmarkArgs(args []string) (BenchmarkOptions, error) {
+	return parseBenchmarkArgs(args, DefaultBenchmarkOptions())
+}
+
+// ParseScalingArgs parses `scaling [depth] [max threads] [hash] [positions file]` arguments.
+func ParseScalingArgs(args []string) (BenchmarkOptions, error) {
+	return parseBenchmarkArgs(args, DefaultScalingOptions())
+}
+
+func parseBenchmarkArgs(args []string, options BenchmarkOptions) (BenchmarkOptions, error) {
+	ints := []*int{&options.Depth, &options.Threads, &options.Hash}
+	for i, arg := range args {
+		if i >= len(ints) {
+			options.PositionsFile = arg
+			break
+		}
+		val, err := strconv.Atoi(arg)
+		if err != nil || val <= 0 {
+			return options, errors.New("Invalid bench argument " + arg)
+		}
+		*ints[i] = val
+	}
+	if options.Depth >= MAX_HEIGHT {
+		return options, errors.New("bench depth out of range")
 	}
+	return options, nil
+}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+func (options *BenchmarkOptions) positions() ([]string, error) {
+	if options.PositionsFile != "" {
+		return loadBenchPositions(options.PositionsFile)
 	}
-	return
+	return benchPositions[:], nil
 }
 
-func Benchmark() {
+// runBenchmark returns total number of nodes searched by all threads and time spent in search
+func runBenchmark(fens []string, depth, threads, hash int) (nodes int, duration time.Duration) {
 	engine := NewEngine()
-	engine.Threads.Val = 1
-	engine.Hash.Val = 256
-	epds := loadEPD("./engine/test_positions/WinAtChess.epd")
-	start := time.Now()
-	nodes := 0
-	for _, entry := range epds {
-		tmpNodes := 0
+	engine.Threads.Val = threads
+	engine.Hash.Val = hash
+	defer engine.closeThreads()
+	for _, fen := range fens {
 		engine.NewGame()
-		engine.Update = func(si SearchInfo) {
-			tmpNodes = si.Nodes
-		}
-		engine.Search(context.Background(), SearchParams{Positions: []Position{entry.Position}, Limits: LimitsType{Depth: 5}})
-		nodes += tmpNodes
+		// Only search is timed, as NewGame spends most of its time allocating tables
+		start := time.Now()
+		engine.Search(context.Background(), SearchParams{Positions: []Position{ParseFen(fen)}, Limits: LimitsType{Depth: depth}})
+		duration += time.Since(start)
+		nodes += engine.nodes()
 	}
-	duration := time.Since(start)
-	fmt.Printf("Time\t:\t%d\n", duration.Nanoseconds()/1e6)
+	return
+}
+
+// Benchmark searches every position to a fixed depth and returns total number of nodes.
+// With a single thread node count is deterministic and is used as a signature of the search.
+func Benchmark(options BenchmarkOptions) (int, error) {
+	fens, err := options.positions()
+	if err != nil {
+		return 0, err
+	}
+	nodes, duration := runBenchmark(fens, options.Depth, options.Threads, options.Hash)
+	fmt.Printf("Time\t:\t%d\n", duration.Milliseconds())
 	fmt.Printf("Nodes\t:\t%d\n", nodes)
-	fmt.Printf("NPS\t:\t%d\n", int64(nodes)/(duration.Nanoseconds()/1e9))
+	fmt.Printf("NPS\t:\t%d\n", int64(float64(nodes)/duration.Seconds()))
+	return nodes, nil
+}
+
+// ScalingBenchmark runs benchmark with every number of threads from 1 to options.Threads
+// and prints speedups of nodes per second and of time to depth relative to a single thread.
+func ScalingBenchmark(options BenchmarkOptions, w io.Writer) error {
+	fens, err := options.positions()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "Threads\tTime\tNodes\tNPS\tNPS speedup\tTTD speedup")
+	var baseNps float64
+	var baseDuration time.Duration
+	for threads := 1; threads <= options.Threads; threads++ {
+		nodes, duration := runBenchmark(fens, options.Depth, threads, options.Hash)
+		nps := float64(nodes) / duration.Seconds()
+		if threads == 1 {
+			baseNps, baseDuration = nps, duration
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%.2f\t%.2f\n", threads, duration.Milliseconds(), nodes, int64(nps),
+			nps/baseNps, baseDuration.Seconds()/duration.Seconds())
+	}
+	return nil
 }
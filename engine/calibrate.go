@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+
+	. "github.com/mhib/combusken/backend"
+)
+
+// Elo of skill levels is estimated by self-play matches between neighbouring levels.
+// Elo differences are chained, so level 20 has Elo of the anchor,
+// and a line through the anchor is fitted to levels to get slope of UCI_Elo mapping.
+// Difference of a match with zero or perfect score is not measured, only bounded,
+// so it and all weaker levels are left out of the fit.
+
+type CalibrationOptions struct {
+	// Games played by every pair of levels, colours are swapped after every game
+	Games int
+	// Time of every move in milliseconds
+	MoveTime int
+	// Elo of full strength engine
+	AnchorElo int
+	// Difference of levels of neighbouring players
+	LevelStep int
+	Seed      int64
+}
+
+func DefaultCalibrationOptions() CalibrationOptions {
+	return CalibrationOptions{Games: 20, MoveTime: 100, AnchorElo: 2800, LevelStep: 4, Seed: 1}
+}
+
+// Games longer than this are adjudicated as draws
+const maxGamePlies = 300
+
+// Number of random plies played before every pair of games
+const openingPlies = 4
+
+// Calibrate plays matches and returns estimated Elo of levels that differ from the maximum one by multiples of LevelStep
+func Calibrate(options CalibrationOptions, w io.Writer) map[int]float64 {
+	rng := rand.New(rand.NewSource(options.Seed))
+	elos := map[int]float64{MaxSkillLevel: float64(options.AnchorElo)}
+	measured := map[int]float64{MaxSkillLevel: float64(options.AnchorElo)}
+	for level := MaxSkillLevel - options.LevelStep; level >= 0; level -= options.LevelStep {
+		stronger := level + options.LevelStep
+		score := 0.0
+		var opening []Position
+		for game := 0; game < options.Games; game++ {
+			if game%2 == 0 {
+				opening = randomOpening(rng)
+			}
+			// Weaker level plays white in even games
+			score += playGame(opening, [2]int{stronger, level}, game%2 == 0, options.MoveTime, rng.Int63())
+		}
+		fraction := score / float64(options.Games)
+		// Perfect scores are moved by half a game to keep Elo difference finite
+		fraction = math.Max(math.Min(fraction, 1-0.5/float64(options.Games)), 0.5/float64(options.Games))
+		difference := -400 * math.Log10(1/fraction-1)
+		elos[level] = elos[stronger] + difference
+		note := ""
+		if _, ok := measured[stronger]; ok && score > 0 && score < float64(options.Games) {
+			measured[level] = elos[level]
+		} else {
+			note = " (bounded, left out of fit)"
+		}
+		fmt.Fprintf(w, "Level %d scored %.1f/%d against level %d, Elo: %.0f%s\n", level, score, options.Games, stronger, elos[level], note)
+	}
+	if len(measured) < 2 {
+		fmt.Fprintln(w, "No level is measured, play more games or use smaller level step")
+		return elos
+	}
+	slope := fitSlope(measured, options.AnchorElo)
+	fmt.Fprintf(w, "Elo = %d - %.1f * (%d - level)\n", options.AnchorElo, slope, MaxSkillLevel)
+	return elos
+}
+
+// playGame returns score of the weaker player
+func playGame(opening []Position, levels [2]int, weakerIsWhite bool, moveTime int, seed int64) float64 {
+	var engines [2]Engine
+	for side := range engines {
+		// levels holds stronger level first
+		level := levels[0]
+		if (side == White) == weakerIsWhite {
+			level = levels[1]
+		}
+		engines[side] = NewEngine()
+		engines[side].Hash.Val = 16
+		engines[side].SkillLevel.Val = level
+		engines[side].NewGame()
+		engines[side].skillRand = rand.New(rand.NewSource(seed + int64(side)))
+		defer engines[side].closeThreads()
+	}
+	positions := append([]Position{}, opening...)
+	whiteScore := 0.5
+	for len(positions) < maxGamePlies {
+		pos := &positions[len(positions)-1]
+		if over, score := gameResult(positions); over {
+			whiteScore = score
+			break
+		}
+		move := engines[pos.SideToMove].Search(context.Background(), SearchParams{Positions: positions, Limits: LimitsType{MoveTime: moveTime}})
+		var child Position
+		if !pos.MakeMove(move, &child) {
+			// Should never happen, but losing on illegal move is a sane fallback
+			whiteScore = float64(pos.SideToMove ^ 1)
+			break
+		}
+		positions = append(positions, child)
+	}
+	if weakerIsWhite {
+		return whiteScore
+	}
+	return 1 - whiteScore
+}
+
+// gameResult returns true and score of white if game is over
+func gameResult(positions []Position) (bool, float64) {
+	pos := &positions[len(positions)-1]
+	if len(GenerateAllLegalMoves(pos)) == 0 {
+		if pos.IsInCheck() {
+			return true, float64(pos.SideToMove ^ 1)
+		}
+		return true, 0.5
+	}
+	if pos.FiftyMove >= 100 ||
+		(pos.Pieces[Pawn]|pos.Pieces[Rook]|pos.Pieces[Queen]) == 0 && !MoreThanOne(pos.Pieces[Knight]|pos.Pieces[Bishop]) {
+		return true, 0.5
+	}
+	repetitions := 0
+	for i := len(positions) - 1; i >= 0 && i >= len(positions)-1-pos.FiftyMove; i-- {
+		if positions[i].Key == pos.Key {
+			repetitions++
+		}
+	}
+	return repetitions >= 3, 0.5
+}
+
+func randomOpening(rng *rand.Rand) []Position {
+	for {
+		positions := []Position{InitialPosition}
+		for len(positions) <= openingPlies {
+			moves := GenerateAllLegalMoves(&positions[len(positions)-1])
+			if len(moves) == 0 {
+				break
+			}
+			var child Position
+			positions[len(positions)-1].MakeLegalMove(moves[rng.Intn(len(moves))].Move, &child)
+			positions = append(positions, child)
+		}
+		if len(positions) > openingPlies {
+			return positions
+		}
+	}
+}
+
+// fitSlope returns slope of least squares line of Elo as a function of level that passes through the anchor
+func fitSlope(elos map[int]float64, anchorElo int) float64 {
+	var sumXX, sumXY float64
+	for level, elo := range elos {
+		x := float64(level - MaxSkillLevel)
+		sumXX += x * x
+		sumXY += x * (elo - float64(anchorElo))
+	}
+	if sumXX == 0 {
+		return 0
+	}
+	return sumXY / sumXX
+}
@@ -2,12 +2,18 @@ package engine
 
 import (
 	"context"
-	"errors"
+	"math/rand"
 	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mhib/combusken/backend"
 	"github.com/mhib/combusken/evaluation"
 	"github.com/mhib/combusken/fathom"
+	"github.com/mhib/combusken/nnue"
+	"github.com/mhib/combusken/tablebase"
 	"github.com/mhib/combusken/transposition"
 
 	. "github.com/mhib/combusken/utils"
@@ -17,28 +23,67 @@ const MAX_HEIGHT = 127
 const STACK_SIZE = MAX_HEIGHT + 1
 const MAX_MOVES = 256
 
-var errTimeout = errors.New("Search timeout")
-
 type Engine struct {
-	Hash              IntOption
-	Threads           IntOption
-	MoveOverhead      IntOption
-	PawnHash          IntOption
-	SyzygyPath        StringOption
-	SyzygyProbeDepth  IntOption
-	done              <-chan struct{}
-	RepeatedPositions map[uint64]interface{}
-	MovesCount        int
-	Update            func(SearchInfo)
+	Hash             IntOption
+	Threads          IntOption
+	MoveOverhead     IntOption
+	PawnHash         IntOption
+	SyzygyPath       StringOption
+	SyzygyProbeDepth IntOption
+	TablebasePath    StringOption
+	Syzygy50MoveRule CheckOption
+	EvalFile         StringOption
+	UseNNUE          CheckOption
+	ShowWDL          CheckOption
+	Contempt         IntOption
+	DynamicContempt  CheckOption
+	AnalyseMode      CheckOption
+	SkillLevel       IntOption
+	LimitStrength    CheckOption
+	Elo              IntOption
+	Deterministic    CheckOption
+	skillRand        *rand.Rand
+	network          *nnue.Network
+	rootMaterial     float64
+	networkError     error
+	done             <-chan struct{}
+	history          []uint64
+	transTable       transposition.TranspositionTable
+	pawnKingTable    evaluation.PawnKingTable
+	tablebase        tablebase.Tablebase
+	Update           func(SearchInfo)
+	events           *eventBus
 	timeManager
+	tablebaseRoot
 	threads []thread
+	// Search is stopped after that many nodes, 0 if unlimited
+	nodeLimit int
+	// Set to 1 when threads should abandon the search, accessed atomically
+	stop int32
 }
 
 type thread struct {
 	engine *Engine
+	// Transposition table is shared by all threads of engine
+	transTable *transposition.TranspositionTable
+	evaluation.Evaluator
+	// Searches are run on a long-lived goroutine started on first use
+	jobs chan func()
 	MoveHistory
 	nodes int
-	stack [STACK_SIZE]StackEntry
+	// Copy of nodes published every 255 nodes and after search for other threads, accessed atomically
+	sharedNodes int64
+	// The highest height reached in current iteration
+	selDepth int
+	// Source of randomness of the thread, seeded in NewGame
+	rand *rand.Rand
+	// Result of the last iteration completed in current search
+	completed result
+	// Draw score for side to move
+	drawValue [backend.White + 1]int
+	stack     [STACK_SIZE]StackEntry
+	// Network accumulators of positions in stack
+	accumulators [STACK_SIZE]nnue.Accumulator
 }
 
 type UciScore struct {
@@ -56,13 +101,25 @@ func newUciScore(score int) UciScore {
 	}
 }
 
+// MarshalJSON encodes score as {"cp": x} or {"mate": x} like in UCI
+func (s UciScore) MarshalJSON() ([]byte, error) {
+	if s.Mate != 0 {
+		return []byte(`{"mate":` + strconv.Itoa(s.Mate) + `}`), nil
+	}
+	return []byte(`{"cp":` + strconv.Itoa(s.Centipawn) + `}`), nil
+}
+
 type SearchInfo struct {
 	Score    UciScore
+	Bound    Bound
 	Depth    int
+	SelDepth int
 	Nodes    int
 	Nps      int
 	Duration int
 	Moves    []backend.Move
+	// Zero unless UCI_ShowWDL is set
+	WDL WDLScore
 }
 
 type StackEntry struct {
@@ -110,7 +167,7 @@ func (e *Engine) GetInfo() (name, version, author string) {
 }
 
 func (e *Engine) GetOptions() []EngineOption {
-	return []EngineOption{&e.Hash, &e.Threads, &e.PawnHash, &e.MoveOverhead, &e.SyzygyPath, &e.SyzygyProbeDepth}
+	return []EngineOption{&e.Hash, &e.Threads, &e.PawnHash, &e.MoveOverhead, &e.SyzygyPath, &e.SyzygyProbeDepth, &e.Syzygy50MoveRule, &e.TablebasePath, &e.EvalFile, &e.UseNNUE, &e.ShowWDL, &e.Contempt, &e.DynamicContempt, &e.AnalyseMode, &e.SkillLevel, &e.LimitStrength, &e.Elo, &e.Deterministic}
 }
 
 func NewEngine() (ret Engine) {
@@ -120,72 +177,182 @@ func NewEngine() (ret Engine) {
 	ret.MoveOverhead = IntOption{"Move Overhead", 0, 10000, 50}
 	ret.SyzygyPath = StringOption{"SyzygyPath", "", false}
 	ret.SyzygyProbeDepth = IntOption{"SyzygyProbeDepth", 0, 100, 0}
+	ret.Syzygy50MoveRule = CheckOption{"Syzygy50MoveRule", true}
+	ret.TablebasePath = StringOption{"TablebasePath", "", false}
+	ret.EvalFile = StringOption{"EvalFile", "", false}
+	ret.UseNNUE = CheckOption{"Use NNUE", true}
+	ret.ShowWDL = CheckOption{"UCI_ShowWDL", false}
+	ret.Contempt = IntOption{"Contempt", -100, 100, 0}
+	ret.DynamicContempt = CheckOption{"Dynamic Contempt", false}
+	ret.AnalyseMode = CheckOption{"UCI_AnalyseMode", false}
+	ret.SkillLevel = IntOption{"Skill Level", 0, MaxSkillLevel, MaxSkillLevel}
+	ret.LimitStrength = CheckOption{"UCI_LimitStrength", false}
+	ret.Elo = IntOption{"UCI_Elo", MinElo, MaxElo, 1500}
+	ret.Deterministic = CheckOption{"Deterministic", false}
 	ret.threads = make([]thread, 1)
 	ret.Update = func(SearchInfo) {}
+	ret.events = &eventBus{}
 	return
 }
 
+// Search returns the best move in the last of given positions
 func (e *Engine) Search(ctx context.Context, searchParams SearchParams) backend.Move {
-	e.fillMoveHistory(searchParams.Positions)
-	e.timeManager = newTimeManager(searchParams.Limits, e.MoveOverhead.Val, searchParams.Positions[len(searchParams.Positions)-1].SideToMove)
-	var cancel context.CancelFunc
-	ctx, cancel = context.WithCancel(ctx)
-	if e.hardTimeout() > 0 {
-		ctx, cancel = context.WithTimeout(ctx, e.hardTimeout())
-	}
-	defer cancel()
-	e.done = ctx.Done()
-	return e.bestMove(ctx, &searchParams.Positions[len(searchParams.Positions)-1])
+	return e.Analyse(ctx, searchParams).BestMove
 }
 
+// fillMoveHistory stores keys of played positions since last irreversible move, oldest first.
+// Root position is not included as it is the first entry of search stack.
 func (e *Engine) fillMoveHistory(positions []backend.Position) {
-	e.MovesCount = len(positions) - 1
-	moveHistory := make(map[uint64]int)
-	for i := len(positions) - 1; i >= 0; i-- {
-		moveHistory[positions[i].Key]++
-		if positions[i].FiftyMove == 0 {
-			break
-		}
+	e.history = e.history[:0]
+	start := len(positions) - 1
+	for start > 0 && positions[start].FiftyMove != 0 {
+		start--
 	}
-	e.RepeatedPositions = make(map[uint64]interface{})
-	for key, count := range moveHistory {
-		if count >= 2 {
-			e.RepeatedPositions[key] = struct{}{}
-		}
+	for i := start; i < len(positions)-1; i++ {
+		e.history = append(e.history, positions[i].Key)
 	}
 }
 
 func (e *Engine) NewGame() {
-	transposition.GlobalTransTable = transposition.NewTransTable(e.Hash.Val)
-	e.threads = make([]thread, e.Threads.Val)
-	for i := range e.threads {
-		e.threads[i].MoveHistory = MoveHistory{}
-		e.threads[i].engine = e
-	}
-	evaluation.GlobalPawnKingTable = evaluation.NewPawnKingTable(e.PawnHash.Val)
-	fathom.MIN_PROBE_DEPTH = e.SyzygyProbeDepth.Val
+	e.transTable = transposition.NewTransTable(e.Hash.Val)
+	e.pawnKingTable = evaluation.NewPawnKingTable(e.PawnHash.Val)
+	// Syzygy tables are global state of fathom, so the last engine to set the path wins
 	if e.SyzygyPath.Dirty {
 		fathom.SetPath(e.SyzygyPath.Val)
 		e.SyzygyPath.Clean()
 	}
+	if e.TablebasePath.Dirty {
+		e.tablebase.SetPath(e.TablebasePath.Val)
+		e.TablebasePath.Clean()
+	}
+	if e.EvalFile.Dirty {
+		e.network, e.networkError = nil, nil
+		if e.EvalFile.Val != "" {
+			e.network, e.networkError = nnue.Load(e.EvalFile.Val)
+		}
+		e.EvalFile.Clean()
+	}
+	var network *nnue.Network
+	if e.UseNNUE.Val {
+		network = e.network
+	}
+	// Workers are kept between games unless number of threads changes
+	if len(e.threads) != e.Threads.Val {
+		e.closeThreads()
+		e.threads = make([]thread, e.Threads.Val)
+	}
+	seed := e.seed()
+	for i := range e.threads {
+		e.threads[i].MoveHistory = MoveHistory{}
+		e.threads[i].engine = e
+		e.threads[i].transTable = &e.transTable
+		e.threads[i].Evaluator = evaluation.Evaluator{PawnKingTable: &e.pawnKingTable, Network: network}
+		e.threads[i].rand = rand.New(rand.NewSource(seed + int64(i)))
+	}
+	e.skillRand = rand.New(rand.NewSource(seed))
 	runtime.GC()
 }
 
+// randomSeed seeds random choices of search when Deterministic is not set.
+// Tests replace it to check that deterministic search does not depend on it.
+var randomSeed = func() int64 {
+	return time.Now().UnixNano()
+}
+
+// seed returns seed of random choices made in the game.
+// With Deterministic set it is fixed, so that single threaded searches to a given depth
+// visit the same nodes and return the same moves in every run.
+func (e *Engine) seed() int64 {
+	if e.Deterministic.Val {
+		return 0
+	}
+	return randomSeed()
+}
+
+// EvaluationInfo describes evaluation used in search, empty if network file is not set
+func (e *Engine) EvaluationInfo() string {
+	if e.networkError != nil {
+		return "Classical evaluation, could not load " + e.EvalFile.Val + ": " + e.networkError.Error()
+	} else if e.network == nil {
+		return ""
+	} else if e.threads[0].Network == nil {
+		return "Classical evaluation, NNUE is disabled"
+	}
+	return "NNUE evaluation using " + e.EvalFile.Val
+}
+
+// nodes returns number of nodes searched by all threads, it is exact only when threads are idle
 func (e *Engine) nodes() (sum int) {
 	for i := range e.threads {
-		sum += e.threads[i].nodes
+		sum += int(atomic.LoadInt64(&e.threads[i].sharedNodes))
 	}
 	return
 }
 
+func (t *thread) publishNodes() {
+	atomic.StoreInt64(&t.sharedNodes, int64(t.nodes))
+}
+
 func (t *thread) incNodes() {
 	t.nodes++
 	if (t.nodes % 255) == 0 {
+		t.publishNodes()
 		select {
 		case <-t.engine.done:
-			panic(errTimeout)
+			t.engine.stopSearch()
 		default:
 		}
+		if t.engine.nodeLimit > 0 && t.engine.nodes() >= t.engine.nodeLimit {
+			t.engine.stopSearch()
+		}
+	}
+}
+
+// stopSearch makes all threads unwind from search as soon as possible
+func (e *Engine) stopSearch() {
+	atomic.StoreInt32(&e.stop, 1)
+}
+
+// stopped reports whether search should be abandoned.
+// Values returned by search after it is set are meaningless and must not be stored.
+func (e *Engine) stopped() bool {
+	return atomic.LoadInt32(&e.stop) != 0
+}
+
+// work runs jobs sent to thread until it is closed
+func (t *thread) work() {
+	for job := range t.jobs {
+		job()
+	}
+}
+
+// runThreads executes fn on workers of the first count threads and returns group that is done when all of them finish
+func (e *Engine) runThreads(count int, fn func(t *thread, idx int)) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	wg.Add(count)
+	for i := 0; i < count; i++ {
+		t := &e.threads[i]
+		if t.jobs == nil {
+			t.jobs = make(chan func())
+			go t.work()
+		}
+		idx := i
+		t.jobs <- func() {
+			defer wg.Done()
+			fn(t, idx)
+			t.publishNodes()
+		}
+	}
+	return &wg
+}
+
+// closeThreads terminates workers of threads
+func (e *Engine) closeThreads() {
+	for i := range e.threads {
+		if e.threads[i].jobs != nil {
+			close(e.threads[i].jobs)
+			e.threads[i].jobs = nil
+		}
 	}
 }
 
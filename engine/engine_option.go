@@ -33,6 +33,11 @@ func (option *IntOption) SetValue(value string) error {
 	if err != nil {
 		return errors.New("Invalid setoption arguments")
 	}
+	return option.Set(v)
+}
+
+// Set is a typed equivalent of SetValue
+func (option *IntOption) Set(v int) error {
 	if v < option.Min || v > option.Max {
 		return errors.New("argument out of range")
 	}
@@ -66,7 +71,40 @@ func (option *StringOption) Clean() {
 }
 
 func (option *StringOption) SetValue(value string) error {
-	option.Val = value
+	option.Set(value)
+	return nil
+}
+
+// Set is a typed equivalent of SetValue
+func (option *StringOption) Set(v string) {
+	option.Val = v
 	option.Dirty = true
+}
+
+type CheckOption struct {
+	Name string
+	Val  bool
+}
+
+func (option *CheckOption) ToUci() string {
+	return fmt.Sprintf("option name %v type %v default %v",
+		option.Name, "check", option.Val)
+}
+
+func (option *CheckOption) GetName() string {
+	return option.Name
+}
+
+func (option *CheckOption) SetValue(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.New("Invalid setoption arguments")
+	}
+	option.Set(v)
 	return nil
 }
+
+// Set is a typed equivalent of SetValue
+func (option *CheckOption) Set(v bool) {
+	option.Val = v
+}
@@ -1,14 +1,60 @@
 package engine
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/fathom"
+	"github.com/mhib/combusken/nnue"
+	"github.com/mhib/combusken/tablebase"
+	. "github.com/mhib/combusken/utils"
 )
 
+type positionTest struct {
+	Position
+	bestMoves []string
+	id        string
+}
+
+func loadEPD(fileLocation string) (res []positionTest) {
+	path, _ := filepath.Abs(fileLocation)
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var entry positionTest
+		positionAndRest := strings.Split(line, " bm ")
+		entry.Position = ParseFen(positionAndRest[0])
+		bestMovesAndID := strings.Split(positionAndRest[1], ";")
+		entry.bestMoves = strings.Split(bestMovesAndID[0], " ")
+		entry.id = bestMovesAndID[1]
+		res = append(res, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return
+}
+
 func TestWAC(t *testing.T) {
 	var good, bad int
 	engine := NewEngine()
@@ -109,3 +155,772 @@ func ParseMoveSAN(pos *Position, san string) Move {
 	}
 	return NullMove
 }
+
+// Positions where the only way to gain is a quiet move giving check
+var quietCheckTactics = []string{
+	// Back rank mate
+	"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+	// Knight mate
+	"6rk/6pp/8/6N1/8/8/8/6K1 w - - 0 1",
+	// Queen mate supported by king
+	"k7/8/1K6/8/8/8/7Q/8 w - - 0 1",
+	// Discovered mate
+	"6rk/6p1/8/8/7N/8/8/6KR w - - 0 1",
+}
+
+func TestQuiescenceQuietChecks(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.NewGame()
+	thread := &engine.threads[0]
+	for _, fen := range quietCheckTactics {
+		thread.stack[0].position = ParseFen(fen)
+		// Quiescence expects root of search to be reached by a move in order to evaluate it
+		thread.stack[0].position.LastMove = WhiteKingSideCastle
+		engine.transTable.Clear()
+		if val := thread.quiescence(QSDepthNoChecks, -Mate, Mate, 0, false); val >= ValueWin {
+			t.Errorf("%s: unexpected mate score %d without quiet checks", fen, val)
+		}
+		engine.transTable.Clear()
+		if val := thread.quiescence(QSDepthChecks, -Mate, Mate, 0, false); val < ValueWin {
+			t.Errorf("%s: mate not found with quiet checks, score %d", fen, val)
+		}
+	}
+}
+
+// Quiet checks are tried only at first ply of quiescence, so they add a bounded number of nodes
+func TestQuiescenceQuietChecksNodes(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.NewGame()
+	thread := &engine.threads[0]
+	entries := loadEPD("./test_positions/WinAtChess.epd")
+	engine.nodeLimit = 20 * len(entries)
+	searchNodes := func(depth int) int {
+		thread.nodes = 0
+		for _, entry := range entries {
+			thread.stack[0].position = entry.Position
+			thread.stack[0].position.LastMove = WhiteKingSideCastle
+			engine.transTable.Clear()
+			thread.quiescence(depth, -Mate, Mate, 0, entry.Position.IsInCheck())
+			if engine.stopped() {
+				t.Fatalf("Quiescence at depth %d exceeded %d nodes", depth, engine.nodeLimit)
+			}
+		}
+		return thread.nodes
+	}
+	withoutChecks := searchNodes(QSDepthNoChecks)
+	withChecks := searchNodes(QSDepthChecks)
+	if withChecks <= withoutChecks {
+		t.Errorf("Quiet checks are not searched, %d nodes with and %d without them", withChecks, withoutChecks)
+	}
+}
+
+func playMoves(t *testing.T, moves string) []Position {
+	positions := []Position{ParseFen(InitialPositionFen)}
+	for _, lan := range strings.Fields(moves) {
+		next, ok := positions[len(positions)-1].MakeMoveLAN(lan)
+		if !ok {
+			t.Fatalf("Illegal move %s", lan)
+		}
+		positions = append(positions, next)
+	}
+	return positions
+}
+
+func TestRepetitionWithGameHistory(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.NewGame()
+	thread := &engine.threads[0]
+
+	// Search line returns to position played once before root
+	positions := playMoves(t, "g1f3 g8f6 f3g1 f6g8")
+	engine.fillMoveHistory(positions[:4])
+	thread.stack[0].position = positions[3]
+	thread.stack[1].position = positions[4]
+	if thread.isDraw(1) {
+		t.Error("Twofold repetition of position played before root treated as a draw")
+	}
+
+	// Search line returns to position played twice before root
+	positions = playMoves(t, "g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8")
+	engine.fillMoveHistory(positions[:8])
+	thread.stack[0].position = positions[7]
+	thread.stack[1].position = positions[8]
+	if !thread.isDraw(1) {
+		t.Error("Threefold repetition of position played before root not detected")
+	}
+
+	// Search line returns to position reached inside the search
+	positions = playMoves(t, "g1f3 g8f6 f3g1 f6g8")
+	engine.fillMoveHistory(positions[:1])
+	for i := 0; i <= 4; i++ {
+		thread.stack[i].position = positions[i]
+	}
+	if !thread.isDraw(4) {
+		t.Error("Repetition inside the search not detected")
+	}
+
+	// Root position repeated once is not a draw
+	engine.fillMoveHistory(positions)
+	thread.stack[0].position = positions[4]
+	if thread.isDraw(0) {
+		t.Error("Twofold repetition of root position treated as a draw")
+	}
+
+	// Root position repeated twice is a draw
+	positions = playMoves(t, "g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8")
+	engine.fillMoveHistory(positions)
+	thread.stack[0].position = positions[8]
+	if !thread.isDraw(0) {
+		t.Error("Threefold repetition of root position not detected")
+	}
+
+	// Irreversible move resets history
+	positions = playMoves(t, "g1f3 g8f6 f3g1 f6g8 e2e4 e7e5")
+	engine.fillMoveHistory(positions)
+	thread.stack[0].position = positions[6]
+	if len(engine.history) != 0 || thread.isDraw(0) {
+		t.Error("Repetition detected across irreversible move")
+	}
+}
+
+func TestRepetitionAfterNullMove(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.NewGame()
+	thread := &engine.threads[0]
+	engine.fillMoveHistory([]Position{InitialPosition})
+	// Empty moves are null moves
+	line := []string{"g1f3", "", "f3g1", "", "g1f3", "g8f6", "f3g1", "f6g8"}
+	thread.stack[0].position = InitialPosition
+	for i, move := range line {
+		pos := &thread.stack[i].position
+		if move == "" {
+			pos.MakeNullMove(&thread.stack[i+1].position)
+			continue
+		}
+		child, ok := pos.MakeMoveLAN(move)
+		if !ok {
+			t.Fatalf("Illegal move %s", move)
+		}
+		thread.stack[i+1].position = child
+	}
+	if thread.stack[4].position.Key != InitialPosition.Key || thread.isDraw(4) {
+		t.Error("Repetition detected across null move")
+	}
+	if !thread.isDraw(8) {
+		t.Error("Repetition after the last null move not detected")
+	}
+}
+
+func TestUpcomingRepetition(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.NewGame()
+	thread := &engine.threads[0]
+	positions := playMoves(t, "b1c3 b8c6 g1f3 g8f6 f3g1")
+	engine.fillMoveHistory(positions[:1])
+	for i := range positions {
+		thread.stack[i].position = positions[i]
+	}
+	// Black can play Nf6-g8 and repeat position after Nc3 Nc6
+	if !thread.hasUpcomingRepetition(5) {
+		t.Error("Upcoming repetition not detected")
+	}
+	if thread.hasUpcomingRepetition(4) {
+		t.Error("Unexpected upcoming repetition")
+	}
+}
+
+// generateTablebase generates 3 piece tables in a temporary directory
+func generateTablebase(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tablebase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tablebase.Generate(dir, 3, ioutil.Discard); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestTablebaseRootProbe(t *testing.T) {
+	dir := generateTablebase(t)
+	defer os.RemoveAll(dir)
+
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.TablebasePath.SetValue(dir)
+	var score UciScore
+	engine.Update = func(info SearchInfo) { score = info.Score }
+	engine.NewGame()
+
+	pos := ParseFen("8/8/8/8/3k4/8/8/1Q4K1 w - - 0 1")
+	move := engine.Search(context.Background(), SearchParams{Positions: []Position{pos}, Limits: LimitsType{Depth: 1}})
+	if move == NullMove || score.Mate <= 0 {
+		t.Errorf("Expected mate score from tablebase, got %v", score)
+	}
+	var child Position
+	if !pos.MakeMove(move, &child) {
+		t.Fatalf("Illegal move %v", move)
+	}
+	if ok, _, dtm := engine.tablebase.ProbeDTM(&child); !ok || dtm != 2*score.Mate-2 {
+		t.Errorf("Move %v does not lead to mate in %d", move, score.Mate)
+	}
+}
+
+func TestTablebaseRootFiltering(t *testing.T) {
+	dir := generateTablebase(t)
+	defer os.RemoveAll(dir)
+
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.TablebasePath.SetValue(dir)
+	engine.NewGame()
+
+	// Rook is attacked, most moves draw
+	pos := ParseFen("8/8/8/5K2/8/8/1k6/2R5 w - - 0 1")
+	rootMoves := GenerateAllLegalMoves(&pos)
+	filtered := engine.rankRootMoves(&pos, rootMoves)
+	if !engine.inTablebase || engine.probeInSearch || engine.tablebaseRoot.score < ValueWin {
+		t.Errorf("Unexpected tablebase root state %+v", engine.tablebaseRoot)
+	}
+	if len(filtered) == 0 || len(filtered) >= len(rootMoves) {
+		t.Fatalf("Expected some of %d moves, got %d", len(rootMoves), len(filtered))
+	}
+	var child Position
+	for _, move := range filtered {
+		pos.MakeLegalMove(move.Move, &child)
+		if ok, wdl, dtm := engine.tablebase.ProbeDTM(&child); !ok || wdl != tablebase.TB_LOSS || Mate-dtm-1 != engine.tablebaseRoot.score {
+			t.Errorf("Move %v does not preserve the fastest win", move.Move)
+		}
+	}
+
+	// Position with castling rights is not ranked
+	pos = ParseFen("4k3/8/8/8/8/8/8/R3K3 w Q - 0 1")
+	rootMoves = GenerateAllLegalMoves(&pos)
+	if filtered = engine.rankRootMoves(&pos, rootMoves); len(filtered) != len(rootMoves) || engine.inTablebase {
+		t.Error("Position with castling rights should not be ranked")
+	}
+}
+
+func TestSyzygyRootScore(t *testing.T) {
+	// Wins are reported in the range of tablebase wins found in search, faster ones are higher
+	win := newUciScore(syzygyScore(fathom.TB_WIN_SCORE, 5))
+	slower := newUciScore(syzygyScore(fathom.TB_WIN_SCORE, 20))
+	if win.Mate != 0 || win.Centipawn != ValueWin-6 || slower.Centipawn >= win.Centipawn {
+		t.Errorf("Unexpected scores of tablebase wins %+v %+v", win, slower)
+	}
+	if loss := newUciScore(syzygyScore(-fathom.TB_WIN_SCORE, 5)); loss.Centipawn != -win.Centipawn {
+		t.Errorf("Expected loss scored as negated win, got %+v", loss)
+	}
+	// Cursed wins keep small score
+	if cursed := syzygyScore(10, 120); cursed != 10 {
+		t.Errorf("Expected cursed win to keep its score, got %d", cursed)
+	}
+}
+
+func TestNetworkSearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rng := rand.New(rand.NewSource(1))
+	net := nnue.NewNetwork(16, 4)
+	for i := range net.FeatureWeights {
+		net.FeatureWeights[i] = int16(rng.Intn(201) - 100)
+	}
+	path := filepath.Join(dir, "network.nnue")
+	if err = net.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.Threads.Val = 1
+	engine.EvalFile.SetValue(path)
+	engine.UseNNUE.Val = true
+	engine.NewGame()
+	defer engine.closeThreads()
+	if engine.networkError != nil {
+		t.Fatal(engine.networkError)
+	}
+	pos := InitialPosition
+	move := engine.Search(context.Background(), SearchParams{Positions: []Position{pos}, Limits: LimitsType{Depth: 4}})
+	var child Position
+	if !pos.MakeMove(move, &child) {
+		t.Errorf("Illegal move %v", move)
+	}
+	// Accumulators in stack are updated incrementally from the root
+	thread := &engine.threads[0]
+	for height := 0; height < 2; height++ {
+		stacked := &thread.stack[height].position
+		if stacked.Accumulator != &thread.accumulators[height] || !stacked.Accumulator.IsComputed(engine.network) {
+			t.Fatalf("Accumulator at height %d is not computed", height)
+		}
+		expected := *stacked
+		expected.Accumulator = new(nnue.Accumulator)
+		expected.RefreshAccumulator(engine.network)
+		if expected.Accumulator.Values != stacked.Accumulator.Values {
+			t.Errorf("Accumulator at height %d differs from computed from scratch", height)
+		}
+	}
+}
+
+func TestShowWDL(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	var infos []SearchInfo
+	engine.Update = func(info SearchInfo) { infos = append(infos, info) }
+	engine.NewGame()
+	params := SearchParams{Positions: []Position{InitialPosition}, Limits: LimitsType{Depth: 3}}
+
+	engine.Search(context.Background(), params)
+	for _, info := range infos {
+		if info.WDL != (WDLScore{}) {
+			t.Errorf("Unexpected WDL %+v when option is disabled", info.WDL)
+		}
+	}
+
+	infos = nil
+	engine.ShowWDL.SetValue("true")
+	engine.Search(context.Background(), params)
+	for _, info := range infos {
+		if info.WDL.Win+info.WDL.Draw+info.WDL.Loss != 1000 || info.WDL.Draw == 0 {
+			t.Errorf("Unexpected WDL %+v for score %+v", info.WDL, info.Score)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.Threads.Val = 2
+	engine.NewGame()
+	params := SearchParams{Positions: []Position{InitialPosition}, Limits: LimitsType{Infinite: true}}
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		move := engine.Search(ctx, params)
+		cancel()
+		var child Position
+		if !InitialPosition.MakeMove(move, &child) {
+			t.Fatalf("Stopped search returned illegal move %s", move.String())
+		}
+	}
+
+	// Stop flag of interrupted search must not affect the next one
+	var depth int
+	engine.Update = func(info SearchInfo) { depth = info.Depth }
+	engine.Search(context.Background(), SearchParams{Positions: []Position{InitialPosition}, Limits: LimitsType{Depth: 6}})
+	if depth != 6 {
+		t.Errorf("Expected search to reach depth 6, got %d", depth)
+	}
+}
+
+func TestBestThread(t *testing.T) {
+	engine := NewEngine()
+	engine.threads = make([]thread, 3)
+	moves := GenerateAllLegalMoves(&InitialPosition)
+	a, b := moves[0].Move, moves[1].Move
+
+	engine.threads[0].completed = result{Move: a, value: 10, depth: 10}
+	engine.threads[1].completed = result{Move: b, value: 30, depth: 11}
+	engine.threads[2].completed = result{Move: b, value: 25, depth: 11}
+	if best := engine.bestThread(); best.Move != b || best.value != 30 {
+		t.Errorf("Expected move found by most threads, got %s %d", best.Move.String(), best.value)
+	}
+
+	engine.threads[0].completed = result{Move: a, value: Mate - 5, depth: 6}
+	if best := engine.bestThread(); best.Move != a {
+		t.Errorf("Expected mating move, got %s", best.Move.String())
+	}
+
+	engine.threads[2].completed = result{Move: b, value: Mate - 3, depth: 4}
+	if best := engine.bestThread(); best.Move != b {
+		t.Errorf("Expected the shortest mate, got %s", best.Move.String())
+	}
+}
+
+func TestSkipDepth(t *testing.T) {
+	for idx := 1; idx <= 20; idx++ {
+		searched := 0
+		for depth := 1; depth <= 12; depth++ {
+			if !skipDepth(idx, depth) {
+				searched++
+			}
+		}
+		if searched == 0 || searched == 12 {
+			t.Errorf("Helper %d searches %d of 12 depths", idx, searched)
+		}
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	defer func(seed func() int64) { randomSeed = seed }(randomSeed)
+	seeds := int64(0)
+	randomSeed = func() int64 {
+		seeds++
+		return seeds
+	}
+	fens := []string{
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	}
+	type searchResult struct {
+		move  Move
+		nodes int
+	}
+	run := func() (res []searchResult) {
+		engine := NewEngine()
+		engine.Hash.Val = 4
+		engine.Deterministic.Val = true
+		engine.NewGame()
+		for _, fen := range fens {
+			move := engine.Search(context.Background(), SearchParams{Positions: []Position{ParseFen(fen)}, Limits: LimitsType{Depth: 8}})
+			res = append(res, searchResult{move, engine.nodes()})
+		}
+		// Limited strength picks a random move
+		engine.SkillLevel.Val = 2
+		engine.NewGame()
+		for i := 0; i < 3; i++ {
+			move := engine.Search(context.Background(), SearchParams{Positions: []Position{InitialPosition}, Limits: LimitsType{Depth: 4}})
+			res = append(res, searchResult{move, engine.nodes()})
+		}
+		return
+	}
+
+	expected := run()
+	for i := 0; i < 2; i++ {
+		if actual := run(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+			t.Errorf("Expected the same moves and node counts in every run, got %v and %v", expected, actual)
+		}
+	}
+	if seeds != 0 {
+		t.Error("Deterministic search should not use random seed")
+	}
+}
+
+func TestConcurrentEngines(t *testing.T) {
+	fens := []string{
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	}
+	search := func(fen string) string {
+		engine := NewEngine()
+		engine.Hash.Val = 4
+		engine.PawnHash.Val = 1
+		engine.Deterministic.Val = true
+		engine.NewGame()
+		defer engine.closeThreads()
+		move := engine.Search(context.Background(), SearchParams{Positions: []Position{ParseFen(fen)}, Limits: LimitsType{Depth: 9}})
+		return fmt.Sprintf("%s %d", move.String(), engine.nodes())
+	}
+
+	expected := make([]string, len(fens))
+	for i, fen := range fens {
+		expected[i] = search(fen)
+	}
+	// Engines do not share any tables, so searches are the same as when they are run one by one
+	actual := make([]string, len(fens))
+	var wg sync.WaitGroup
+	for i := range fens {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			actual[idx] = search(fens[idx])
+		}(i)
+	}
+	wg.Wait()
+	for i := range fens {
+		if actual[i] != expected[i] {
+			t.Errorf("%s: expected %s, got %s when run concurrently", fens[i], expected[i], actual[i])
+		}
+	}
+}
+
+func TestAnalyse(t *testing.T) {
+	engine := New()
+	defer engine.Close()
+	if err := engine.Hash.Set(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Threads.Set(0); err == nil {
+		t.Error("Expected error for value out of range")
+	}
+	engine.NewGame()
+	events, unsubscribe := engine.Subscribe(MAX_HEIGHT)
+	res := engine.Analyse(context.Background(), SearchParams{Positions: []Position{InitialPosition}, Limits: LimitsType{Depth: 8}})
+	unsubscribe()
+	unsubscribe()
+
+	if res.BestMove == NullMove || res.Depth != 8 || res.Bound != BoundExact || res.Nodes != engine.nodes() {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	if len(res.PV) < 2 || res.PV[0] != res.BestMove || res.PonderMove != res.PV[1] {
+		t.Errorf("Expected ponder move from PV, got %v %v", res.PonderMove, res.PV)
+	}
+	if res.SelDepth < res.Depth {
+		t.Errorf("Expected seldepth to be at least depth, got %d", res.SelDepth)
+	}
+	var last SearchInfo
+	count := 0
+	for info := range events {
+		last = info
+		count++
+	}
+	if count != 8 || last.Depth != res.Depth || last.Score != res.Score || fmt.Sprint(last.Moves) != fmt.Sprint(res.PV) {
+		t.Errorf("Expected event for every depth ending with result, got %d events, last %+v", count, last)
+	}
+}
+
+func TestAnalyseBatch(t *testing.T) {
+	lines := []string{
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1 bm e2a6; id \"kiwipete\";",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - -",
+		"invalid",
+		"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3",
+	}
+	input := "# comment\n\n" + strings.Join(lines, "\n") + "\n"
+	var output bytes.Buffer
+	options := BatchOptions{Workers: 3, Hash: 4, Limits: LimitsType{Depth: 7}}
+	if err := AnalyseBatch(context.Background(), strings.NewReader(input), &output, options); err != nil {
+		t.Fatal(err)
+	}
+	results := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(results) != len(lines) {
+		t.Fatalf("Expected %d results, got %d", len(lines), len(results))
+	}
+
+	// Results do not depend on worker and order of positions
+	e := newBatchEngine(options)
+	defer e.Close()
+	for i := len(lines) - 1; i >= 0; i-- {
+		var res, expected map[string]interface{}
+		if err := json.Unmarshal([]byte(results[i]), &res); err != nil {
+			t.Fatal(err)
+		}
+		expectedJSON, _ := json.Marshal(e.analysePosition(context.Background(), fenFromEPD(lines[i]), options.Limits))
+		json.Unmarshal(expectedJSON, &expected)
+		delete(res, "time")
+		delete(expected, "time")
+		if fmt.Sprint(res) != fmt.Sprint(expected) {
+			t.Errorf("Expected %s, got %s", expectedJSON, results[i])
+		}
+	}
+	if !strings.Contains(results[3], `"error"`) || !strings.Contains(results[4], `"error"`) {
+		t.Errorf("Expected errors for invalid and mated positions, got %s %s", results[3], results[4])
+	}
+}
+
+func TestNodeLimit(t *testing.T) {
+	engine := New()
+	defer engine.Close()
+	engine.Hash.Set(4)
+	// Threads are not limited by number of cpus, so that helpers are run with -race on every machine
+	for _, threads := range []int{1, 3} {
+		engine.Threads.Val = threads
+		engine.NewGame()
+		res := engine.Analyse(context.Background(), SearchParams{Positions: []Position{InitialPosition}, Limits: LimitsType{Nodes: 20000}})
+		if res.Nodes < 20000 || res.Nodes > 20000+threads*255 || res.BestMove == NullMove {
+			t.Errorf("Expected search with %d threads to stop just after node limit, got %+v", threads, res)
+		}
+	}
+}
+
+func TestReportedNodes(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.Threads.Val = 1
+	var infos []SearchInfo
+	engine.Update = func(info SearchInfo) { infos = append(infos, info) }
+	engine.NewGame()
+	engine.Search(context.Background(), SearchParams{Positions: []Position{InitialPosition}, Limits: LimitsType{Depth: 8}})
+	if len(infos) == 0 || infos[0].Nodes == 0 {
+		t.Fatalf("Expected nodes of the first iteration to be reported, got %+v", infos)
+	}
+	// Search stops right after the last iteration is reported
+	if last := infos[len(infos)-1]; last.Nodes != engine.threads[0].nodes {
+		t.Errorf("Expected exact node count %d, got %d", engine.threads[0].nodes, last.Nodes)
+	}
+}
+
+func TestContempt(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.Contempt.Val = 30
+	engine.NewGame()
+	thread := &engine.threads[0]
+	white := InitialPosition
+	black := ParseFen("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+
+	for _, root := range []Position{white, black} {
+		thread.stack[0].position = root
+		thread.updateContempt(1, 0)
+		for _, pos := range []Position{white, black} {
+			expected := 30
+			if pos.SideToMove == root.SideToMove {
+				expected = -30
+			}
+			if res := thread.contempt(&pos, 0); res != expected {
+				t.Errorf("Expected draw score %d for side %d with root side %d, got %d", expected, pos.SideToMove, root.SideToMove, res)
+			}
+		}
+	}
+
+	// Dynamic contempt grows when root side is winning
+	engine.DynamicContempt.Val = true
+	thread.stack[0].position = white
+	thread.updateContempt(WindowDepth, 200)
+	if res := thread.contempt(&white, 0); res != -30-dynamicContemptScale/2 {
+		t.Errorf("Unexpected dynamic draw score %d", res)
+	}
+	thread.updateContempt(WindowDepth, -200)
+	if res := thread.contempt(&white, 0); res != -30+dynamicContemptScale/2 {
+		t.Errorf("Unexpected dynamic draw score %d", res)
+	}
+
+	engine.AnalyseMode.Val = true
+	thread.updateContempt(WindowDepth, 200)
+	if thread.contempt(&white, 0) != 0 || thread.contempt(&black, 0) != 0 {
+		t.Error("Contempt applied in analysis mode")
+	}
+}
+
+func TestContemptRepetition(t *testing.T) {
+	// Black can repeat the position for the third time with f6g8
+	positions := playMoves(t, "g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1")
+	for _, contempt := range []int{-100, 100} {
+		engine := NewEngine()
+		engine.Hash.Val = 4
+		engine.Contempt.Val = contempt
+		engine.NewGame()
+		move := engine.Search(context.Background(), SearchParams{Positions: positions, Limits: LimitsType{Depth: 6}})
+		if repeats := move.String() == "f6g8"; repeats != (contempt < 0) {
+			t.Errorf("Unexpected move %v with contempt %d", move, contempt)
+		}
+	}
+}
+
+func TestSkill(t *testing.T) {
+	engine := NewEngine()
+	if engine.skill().enabled() {
+		t.Error("Strength is limited by default")
+	}
+	engine.LimitStrength.Val = true
+	engine.Elo.Val = MinElo
+	if s := engine.skill(); s.level != 0 {
+		t.Errorf("Expected level 0 for minimal Elo, got %f", s.level)
+	}
+	engine.Elo.Val = MaxElo
+	if engine.skill().enabled() {
+		t.Error("Strength is limited with maximal Elo")
+	}
+
+	candidates := []result{{Move: 1, value: 100}, {Move: 2, value: -100}}
+	rng := rand.New(rand.NewSource(1))
+	counts := map[int]int{}
+	for i := 0; i < 1000; i++ {
+		if (skill{0}).pick(candidates, rng).Move == 2 {
+			counts[0]++
+		}
+		if (skill{MaxSkillLevel - 1}).pick(candidates, rng).Move == 2 {
+			counts[MaxSkillLevel-1]++
+		}
+	}
+	if counts[0] < 100 || counts[MaxSkillLevel-1] != 0 {
+		t.Errorf("Unexpected number of worse moves picked %v", counts)
+	}
+}
+
+func TestSkillSearch(t *testing.T) {
+	engine := NewEngine()
+	engine.Hash.Val = 4
+	engine.SkillLevel.Val = 0
+	maxDepth := 0
+	var last SearchInfo
+	engine.Update = func(info SearchInfo) {
+		maxDepth = Max(maxDepth, info.Depth)
+		last = info
+	}
+	engine.NewGame()
+	pos := InitialPosition
+	move := engine.Search(context.Background(), SearchParams{Positions: []Position{pos}, Limits: LimitsType{Depth: 10}})
+	var child Position
+	if !pos.MakeMove(move, &child) {
+		t.Errorf("Illegal move %v", move)
+	}
+	if maxDepth != 1 {
+		t.Errorf("Expected search limited to depth 1, got %d", maxDepth)
+	}
+	// Reported line starts with picked move
+	for i := 0; i < 20; i++ {
+		move = engine.Search(context.Background(), SearchParams{Positions: []Position{pos}, Limits: LimitsType{Depth: 10}})
+		if len(last.Moves) == 0 || last.Moves[0] != move {
+			t.Fatalf("Expected reported line to start with %v, got %v", move, last.Moves)
+		}
+	}
+
+	// Node cap stops iteration in progress
+	engine.SkillLevel.Val = 10
+	engine.Search(context.Background(), SearchParams{Positions: []Position{pos}, Limits: LimitsType{Depth: 10}})
+	if maxNodes := (skill{10}).maxNodes(); engine.nodes() > maxNodes+255 {
+		t.Errorf("Expected search to stop after %d nodes, got %d", maxNodes, engine.nodes())
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	options := DefaultCalibrationOptions()
+	options.Games = 2
+	options.MoveTime = 5
+	options.LevelStep = MaxSkillLevel
+	elos := Calibrate(options, ioutil.Discard)
+	if len(elos) != 2 || elos[MaxSkillLevel] != float64(options.AnchorElo) || elos[0] >= elos[MaxSkillLevel] {
+		t.Errorf("Unexpected calibration result %v", elos)
+	}
+}
+
+func TestParseBenchmarkArgs(t *testing.T) {
+	for _, test := range []struct {
+		args     []string
+		expected BenchmarkOptions
+		valid    bool
+	}{
+		{nil, BenchmarkOptions{Depth: 5, Threads: 1, Hash: 256}, true},
+		{[]string{"8"}, BenchmarkOptions{Depth: 8, Threads: 1, Hash: 256}, true},
+		{[]string{"8", "2", "16"}, BenchmarkOptions{Depth: 8, Threads: 2, Hash: 16}, true},
+		{[]string{"8", "2", "16", "positions.epd"}, BenchmarkOptions{Depth: 8, Threads: 2, Hash: 16, PositionsFile: "positions.epd"}, true},
+		{[]string{"x"}, BenchmarkOptions{}, false},
+		{[]string{"8", "0"}, BenchmarkOptions{}, false},
+		{[]string{"-1"}, BenchmarkOptions{}, false},
+		{[]string{"127"}, BenchmarkOptions{}, false},
+	} {
+		options, err := ParseBenchmarkArgs(test.args)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%v: expected error", test.args)
+			}
+			continue
+		}
+		if err != nil || options != test.expected {
+			t.Errorf("%v: expected %+v, got %+v %v", test.args, test.expected, options, err)
+		}
+	}
+}
+
+func TestBenchmarkDeterministic(t *testing.T) {
+	fens := benchPositions[:4]
+	nodes, _ := runBenchmark(fens, 6, 1, 16)
+	if again, _ := runBenchmark(fens, 6, 1, 16); nodes == 0 || again != nodes {
+		t.Errorf("Expected the same node count in every run, got %d and %d", nodes, again)
+	}
+}
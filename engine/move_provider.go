@@ -15,6 +15,8 @@ const (
 	GENERATE_QUIET
 	QUIET
 	BAD_NOISY
+	GENERATE_CHECKS
+	QUIET_CHECKS
 	DONE
 )
 
@@ -34,6 +36,7 @@ type MoveProvider struct {
 const (
 	NORMAL uint8 = iota
 	NOISY
+	NOISY_AND_CHECKS
 )
 
 var mvvlvaScores = [None + 1]int32{10, 40, 45, 68, 145, 256, 0}
@@ -54,6 +57,13 @@ func (mp *MoveProvider) InitQs() {
 	mp.stage = GENERATE_NOISY
 }
 
+// Quiescence search at depth >= QSDepthChecks also tries quiet checks after good noisy moves
+func (mp *MoveProvider) InitQsChecks() {
+	mp.kind = NOISY_AND_CHECKS
+	mp.ttMove = NullMove
+	mp.stage = GENERATE_NOISY
+}
+
 func (mp *MoveProvider) InitNormal(pos *Position, mh *MoveHistory, height int, ttMove Move) {
 	mp.kind = NORMAL
 	mp.stage = TT_MOVE
@@ -134,6 +144,10 @@ func (mp *MoveProvider) GetNextMove(pos *Position, mh *MoveHistory, depth, heigh
 			mp.stage = DONE
 			return NullMove
 		}
+		if mp.kind == NOISY_AND_CHECKS {
+			mp.stage = GENERATE_CHECKS
+			return mp.GetNextMove(pos, mh, depth, height)
+		}
 		mp.stage++
 		fallthrough
 	case KILLER_1:
@@ -189,6 +203,20 @@ func (mp *MoveProvider) GetNextMove(pos *Position, mh *MoveHistory, depth, heigh
 				return move.Move
 			}
 		}
+		mp.stage = DONE
+		return NullMove
+	case GENERATE_CHECKS:
+		mp.stage++
+		mp.quietsSize = GenerateQuietChecks(pos, mp.Moves[mp.split:])
+		fallthrough
+	case QUIET_CHECKS:
+		for mp.quietsSize > 0 {
+			mp.quietsSize--
+			move = mp.Moves[mp.split+mp.quietsSize]
+			if evaluation.SeeSign(pos, move.Move) {
+				return move.Move
+			}
+		}
 		mp.stage++
 		fallthrough
 	default:
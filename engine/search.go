@@ -1,8 +1,7 @@
 package engine
 
 import (
-	"context"
-	"math/rand"
+	"sync"
 
 	. "github.com/mhib/combusken/backend"
 	. "github.com/mhib/combusken/evaluation"
@@ -59,6 +58,12 @@ func depthToMate(val int) int {
 
 func (t *thread) quiescence(depth, alpha, beta, height int, inCheck bool) int {
 	t.incNodes()
+	if t.engine.stopped() {
+		return 0
+	}
+	if height > t.selDepth {
+		t.selDepth = height
+	}
 	t.stack[height].PV.clear()
 	pos := &t.stack[height].position
 	alphaOrig := alpha
@@ -73,7 +78,7 @@ func (t *thread) quiescence(depth, alpha, beta, height int, inCheck bool) int {
 	} else {
 		ttDepth = QSDepthNoChecks
 	}
-	hashOk, hashValue, hashEval, hashDepth, hashMove, hashFlag := transposition.GlobalTransTable.Get(pos.Key)
+	hashOk, hashValue, hashEval, hashDepth, hashMove, hashFlag := t.transTable.Get(pos.Key)
 	if hashOk && hashValue != UnknownValue && int(hashDepth) >= ttDepth {
 		hashValue = transposition.ValueFromTrans(hashValue, height)
 		if hashFlag == TransExact || (hashFlag == TransAlpha && int(hashValue) <= alpha) ||
@@ -110,12 +115,12 @@ func (t *thread) quiescence(depth, alpha, beta, height int, inCheck bool) int {
 			}
 		} else {
 			if pos.LastMove != NullMove {
-				eval = int16(Evaluate(pos))
+				eval = int16(t.Evaluate(pos))
 			} else {
 				eval = -t.getEvaluation(height-1) + 2*Tempo
 			}
 			bestVal = int(eval)
-			transposition.GlobalTransTable.Set(pos.Key, UnknownValue, eval, transposition.NoneDepth, NullMove, TransNone)
+			t.transTable.Set(pos.Key, UnknownValue, eval, transposition.NoneDepth, NullMove, TransNone)
 		}
 		// Early return if not in check and evaluation exceeded beta
 		if bestVal >= beta {
@@ -124,7 +129,11 @@ func (t *thread) quiescence(depth, alpha, beta, height int, inCheck bool) int {
 		if alpha < bestVal {
 			alpha = bestVal
 		}
-		t.stack[height].InitQs()
+		if depth >= QSDepthChecks {
+			t.stack[height].InitQsChecks()
+		} else {
+			t.stack[height].InitQs()
+		}
 	}
 
 	for {
@@ -137,12 +146,15 @@ func (t *thread) quiescence(depth, alpha, beta, height int, inCheck bool) int {
 		}
 
 		// Prefetch as early as possible
-		transposition.GlobalTransTable.Prefetch(child.Key)
+		t.transTable.Prefetch(child.Key)
 
 		t.SetCurrentMove(height, move)
 		moveCount++
 		childInCheck := child.IsInCheck()
 		val := -t.quiescence(depth-1, -beta, -alpha, height+1, childInCheck)
+		if t.engine.stopped() {
+			return 0
+		}
 		if val > bestVal {
 			bestVal = val
 			bestMove = move
@@ -170,17 +182,37 @@ func (t *thread) quiescence(depth, alpha, beta, height int, inCheck bool) int {
 		flag = TransExact
 	}
 
-	transposition.GlobalTransTable.Set(pos.Key, transposition.ValueToTrans(alpha, height), eval, ttDepth, bestMove, flag)
+	t.transTable.Set(pos.Key, transposition.ValueToTrans(alpha, height), eval, ttDepth, bestMove, flag)
 
 	return alpha
 }
 
-// Currently draws are scored as 0 +/- 1 randomly
+// Draws are scored with contempt from root side perspective and randomly shifted by 1 in deeper nodes,
+// positive contempt makes root side avoid draws
 func (t *thread) contempt(pos *Position, depth int) int {
 	if depth < 4 {
-		return 0
+		return t.drawValue[pos.SideToMove]
 	}
-	return 2*(t.nodes&1) - 1
+	return t.drawValue[pos.SideToMove] + 2*(t.nodes&1) - 1
+}
+
+// Dynamic contempt is increased when root side is winning and decreased when it is losing
+const dynamicContemptScale = 50
+
+// updateContempt sets draw scores of both sides for search iteration, lastValue is score of the previous one
+func (t *thread) updateContempt(depth, lastValue int) {
+	e := t.engine
+	if e.AnalyseMode.Val {
+		t.drawValue = [White + 1]int{}
+		return
+	}
+	contempt := e.Contempt.Val
+	if e.DynamicContempt.Val && depth >= WindowDepth && Abs(lastValue) < ValueWin {
+		contempt += dynamicContemptScale * lastValue / (Abs(lastValue) + 200)
+	}
+	rootSide := t.stack[0].position.SideToMove
+	t.drawValue[rootSide] = -contempt
+	t.drawValue[rootSide^1] = contempt
 }
 
 func moveToFirst(moves []EvaledMove, move Move) {
@@ -198,6 +230,12 @@ func moveCountPruning(improving, depth int) int {
 
 func (t *thread) alphaBeta(depth, alpha, beta, height int, inCheck bool, cutNode bool) int {
 	t.incNodes()
+	if t.engine.stopped() {
+		return 0
+	}
+	if height > t.selDepth {
+		t.selDepth = height
+	}
 	t.stack[height].PV.clear()
 
 	var pos *Position = &t.stack[height].position
@@ -216,8 +254,16 @@ func (t *thread) alphaBeta(depth, alpha, beta, height int, inCheck bool, cutNode
 		return alpha
 	}
 
+	// Side to move can force a repetition
+	if draw := t.contempt(pos, depth); alpha < draw && t.hasUpcomingRepetition(height) {
+		alpha = draw
+		if alpha >= beta {
+			return alpha
+		}
+	}
+
 	alphaOrig := alpha
-	hashOk, hashValue, hashEval, hashDepth, hashMove, hashFlag := transposition.GlobalTransTable.Get(pos.Key)
+	hashOk, hashValue, hashEval, hashDepth, hashMove, hashFlag := t.transTable.Get(pos.Key)
 	var val int
 	if hashOk && hashValue != UnknownValue {
 		hashValue = transposition.ValueFromTrans(hashValue, height)
@@ -237,8 +283,8 @@ func (t *thread) alphaBeta(depth, alpha, beta, height int, inCheck bool, cutNode
 	}
 
 	// Probe tablebase
-	if fathom.IsWDLProbeable(pos, depth) {
-		if tbResult := fathom.ProbeWDL(pos, depth); tbResult != fathom.TB_RESULT_FAILED {
+	if t.engine.probeInSearch {
+		if tbResult := t.engine.probeWDL(pos, depth); tbResult != fathom.TB_RESULT_FAILED {
 			var ttBound int
 			if tbResult == fathom.TB_LOSS {
 				val = ValueLoss + height + 1
@@ -251,7 +297,7 @@ func (t *thread) alphaBeta(depth, alpha, beta, height int, inCheck bool, cutNode
 				ttBound = TransExact
 			}
 			if ttBound == TransExact || ttBound == TransBeta && val >= beta || ttBound == TransAlpha && val <= alpha {
-				transposition.GlobalTransTable.Set(pos.Key, int16(val), UnknownValue, MAX_HEIGHT, NullMove, ttBound)
+				t.transTable.Set(pos.Key, int16(val), UnknownValue, MAX_HEIGHT, NullMove, ttBound)
 				return val
 			}
 		}
@@ -287,12 +333,12 @@ func (t *thread) alphaBeta(depth, alpha, beta, height int, inCheck bool, cutNode
 		}
 	} else {
 		if pos.LastMove != NullMove {
-			eval = int16(Evaluate(pos))
+			eval = int16(t.Evaluate(pos))
 		} else {
 			eval = -t.getEvaluation(height-1) + 2*Tempo
 		}
 		t.setEvaluation(height, eval)
-		transposition.GlobalTransTable.Set(pos.Key, UnknownValue, eval, transposition.NoneDepth, NullMove, TransNone)
+		t.transTable.Set(pos.Key, UnknownValue, eval, transposition.NoneDepth, NullMove, TransNone)
 	}
 
 	if height > 1 {
@@ -370,7 +416,7 @@ afterPreMovesPruning:
 			iiDepth = (depth - 5) / 2
 		}
 		t.alphaBeta(iiDepth, alpha, beta, height, inCheck, cutNode)
-		_, _, _, _, hashMove, _ = transposition.GlobalTransTable.Get(pos.Key)
+		_, _, _, _, hashMove, _ = t.transTable.Get(pos.Key)
 	}
 
 	// Quiet moves are stored in order to reduce their history value at the end of search
@@ -414,7 +460,7 @@ afterPreMovesPruning:
 		t.SetCurrentMove(height, move)
 
 		// Prefetch as early as possible
-		transposition.GlobalTransTable.Prefetch(child.Key)
+		t.transTable.Prefetch(child.Key)
 
 		moveCount++
 		childInCheck := child.IsInCheck()
@@ -469,6 +515,11 @@ afterPreMovesPruning:
 			val = -t.alphaBeta(newDepth, -beta, -alpha, height+1, childInCheck, false)
 		}
 
+		// Values of interrupted search cannot be trusted
+		if t.engine.stopped() {
+			return 0
+		}
+
 		if val > bestVal {
 			bestVal = val
 			bestMove = move
@@ -501,7 +552,7 @@ afterPreMovesPruning:
 	} else {
 		flag = TransExact
 	}
-	transposition.GlobalTransTable.Set(pos.Key, transposition.ValueToTrans(alpha, height), t.getEvaluation(height), depth, bestMove, flag)
+	t.transTable.Set(pos.Key, transposition.ValueToTrans(alpha, height), t.getEvaluation(height), depth, bestMove, flag)
 	return alpha
 }
 
@@ -554,35 +605,95 @@ func (t *thread) isDraw(height int) bool {
 		return true
 	}
 
-	// Look for repetitoin in current search stack
-	for i := height - 1; i >= 0; i-- {
-		descendant := &t.stack[i].position
-		if descendant.Key == pos.Key {
-			return true
+	// King and pawn against king draws are known from bitbase
+	if IsKPKDraw(pos) {
+		return true
+	}
+
+	// Look for repetition in search stack and in already played positions.
+	// Repetition of position reached inside the search is a draw,
+	// position played before root is a draw only if it occurs for the third time.
+	// Null move does not reset fifty move counter, but positions before it cannot be repeated.
+	end := pos.FiftyMove
+	for i := 0; i < Min(end, height); i++ {
+		if t.stack[height-i].position.LastMove == NullMove {
+			end = i
+			break
 		}
-		if descendant.FiftyMove == 0 || descendant.LastMove == NullMove {
-			return false
+	}
+	repetitions := 0
+	for i := 2; i <= end; i += 2 {
+		key, ok := t.keyBefore(height, i)
+		if !ok {
+			break
+		}
+		if key == pos.Key {
+			repetitions++
+			if i <= height || repetitions == 2 {
+				return true
+			}
 		}
 	}
 
-	// Check for repetition in already played positions
-	if _, found := t.engine.RepeatedPositions[pos.Key]; found {
-		return true
+	return false
+}
+
+// keyBefore returns key of position played distance plies before position at height
+func (t *thread) keyBefore(height, distance int) (uint64, bool) {
+	if distance <= height {
+		return t.stack[height-distance].position.Key, true
 	}
+	idx := len(t.engine.history) - (distance - height)
+	if idx < 0 {
+		return 0, false
+	}
+	return t.engine.history[idx], true
+}
 
+// hasUpcomingRepetition checks if side to move has a reversible move that repeats position reached in search
+// https://web.archive.org/web/20201107002606/https://marcelk.net/2013-04-06/paper/upcoming-rep-v2.pdf
+func (t *thread) hasUpcomingRepetition(height int) bool {
+	var pos *Position = &t.stack[height].position
+	end := Min(pos.FiftyMove, height-1)
+	// Cycle cannot go through null move
+	for i := 0; i < end; i++ {
+		if t.stack[height-i].position.LastMove == NullMove {
+			end = i
+			break
+		}
+	}
+	if end < 3 {
+		return false
+	}
+	originalKey := pos.Key
+	other := originalKey ^ t.stack[height-1].position.Key ^ SideToMoveKey()
+	for i := 3; i <= end; i += 2 {
+		other ^= t.stack[height-i+1].position.Key ^ t.stack[height-i].position.Key ^ SideToMoveKey()
+		if other != 0 {
+			continue
+		}
+		found, move := CuckooMove(originalKey ^ t.stack[height-i].position.Key)
+		if found && BetweenBB[move.From()][move.To()]&(pos.Colours[White]|pos.Colours[Black]) == 0 {
+			return true
+		}
+	}
 	return false
 }
 
 type result struct {
 	Move
-	value int
-	depth int
-	moves []Move
+	value    int
+	bound    Bound
+	depth    int
+	selDepth int
+	moves    []Move
 }
 
 // https://www.chessprogramming.org/Aspiration_Windows
 // After a lot of tries ELO gain have been accomplished only with relatively large window(50 cp)
 func (t *thread) aspirationWindow(depth, lastValue int, moves []EvaledMove) result {
+	t.updateContempt(depth, lastValue)
+	t.selDepth = 0
 	var alpha, beta int
 	delta := WindowSize
 	searchDepth := depth
@@ -596,9 +707,14 @@ func (t *thread) aspirationWindow(depth, lastValue int, moves []EvaledMove) resu
 	}
 	for {
 		res := t.depSearch(Max(1, searchDepth), alpha, beta, moves)
-		if res.value > alpha && res.value < beta {
+		if t.engine.stopped() || res.value > alpha && res.value < beta {
 			return res
 		}
+		// Bounds are reported only in long searches, as GUIs would be flooded with them otherwise
+		if t == &t.engine.threads[0] && t.engine.getElapsedTime() >= boundReportDelay {
+			t.publishNodes()
+			t.engine.reportResult(res)
+		}
 		if res.value <= alpha {
 			beta = (alpha + beta) / 2
 			alpha = Max(-Mate, alpha-delta)
@@ -620,7 +736,7 @@ func (t *thread) depSearch(depth, alpha, beta int, moves []EvaledMove) result {
 	alphaOrig := alpha
 	inCheck := pos.IsInCheck()
 	moveCount := 0
-	eval := int16(Evaluate(pos))
+	eval := int16(t.Evaluate(pos))
 	t.setEvaluation(0, eval)
 	t.stack[0].PV.clear()
 	t.ResetKillers(1)
@@ -631,7 +747,7 @@ func (t *thread) depSearch(depth, alpha, beta int, moves []EvaledMove) result {
 	for i := range moves {
 		pos.MakeLegalMove(moves[i].Move, child)
 		// Prefetch as early as possible
-		transposition.GlobalTransTable.Prefetch(child.Key)
+		t.transTable.Prefetch(child.Key)
 
 		t.SetCurrentMove(0, moves[i].Move)
 
@@ -669,13 +785,16 @@ func (t *thread) depSearch(depth, alpha, beta int, moves []EvaledMove) result {
 			bestMove = moves[i].Move
 			if val > alpha {
 				alpha = val
+				t.stack[0].PV.assign(moves[i].Move, &t.stack[1].PV)
 				if alpha >= beta {
 					break
 				}
-				t.stack[0].PV.assign(moves[i].Move, &t.stack[1].PV)
 			}
 		}
 	}
+	if t.engine.stopped() {
+		return result{}
+	}
 	if moveCount == 0 {
 		if inCheck {
 			alpha = lossIn(0)
@@ -689,147 +808,181 @@ func (t *thread) depSearch(depth, alpha, beta int, moves []EvaledMove) result {
 	t.EvaluateMoves(pos, moves, bestMove, 0, depth)
 	sortMoves(moves)
 	var flag int
+	bound := BoundExact
 	if alpha == alphaOrig {
 		flag = TransAlpha
+		bound = BoundUpper
 	} else if alpha >= beta {
 		flag = TransBeta
+		bound = BoundLower
 	} else {
 		flag = TransExact
 	}
-	transposition.GlobalTransTable.Set(pos.Key, transposition.ValueToTrans(alpha, 0), eval, depth, bestMove, flag)
-	return result{bestMove, alpha, depth, cloneMoves(t.stack[0].PV.items[:t.stack[0].PV.size])}
+	t.transTable.Set(pos.Key, transposition.ValueToTrans(alpha, 0), eval, depth, bestMove, flag)
+	return result{
+		Move:     bestMove,
+		value:    alpha,
+		bound:    bound,
+		depth:    depth,
+		selDepth: t.selDepth,
+		moves:    cloneMoves(t.stack[0].PV.items[:t.stack[0].PV.size]),
+	}
 }
 
-func (e *Engine) singleThreadBestMove(ctx context.Context, rootMoves []EvaledMove) Move {
-	var lastBestMove Move
-	thread := e.threads[0]
-	var res result
-	lastValue := -Mate
-	for i := 1; ; i++ {
-		resultChan := make(chan result, 1)
-		go func(depth int) {
-			defer recoverFromTimeout()
-			res = thread.aspirationWindow(depth, lastValue, rootMoves)
-			resultChan <- res
-			lastValue = res.value
-		}(i)
-		select {
-		case <-ctx.Done():
-			return lastBestMove
-		case res := <-resultChan:
-			timeSinceStart := e.getElapsedTime()
-			e.Update(SearchInfo{newUciScore(res.value), res.depth, thread.nodes, int(float64(thread.nodes) / timeSinceStart.Seconds()), int(timeSinceStart.Milliseconds()), res.moves})
-			if res.value >= ValueWin && depthToMate(res.value) <= i {
-				return res.Move
-			}
-			if res.Move == 0 {
-				return lastBestMove
-			}
-			if i >= MAX_HEIGHT {
-				return res.Move
-			}
-			e.updateTime(res.depth, res.value)
-			if e.isSoftTimeout(i, thread.nodes) {
-				return res.Move
-			}
-			lastBestMove = res.Move
-		}
+// Helper threads skip some depths, so that threads do not search the same tree at the same time.
+// Values taken from Stockfish
+var skipSize = [...]int{1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 3, 3, 4, 4, 4, 4, 4, 4, 4, 4}
+var skipPhase = [...]int{0, 1, 0, 1, 2, 3, 0, 1, 2, 3, 4, 5, 0, 1, 2, 3, 4, 5, 6, 7}
+
+func skipDepth(idx, depth int) bool {
+	if idx == 0 {
+		return false
 	}
+	i := (idx - 1) % len(skipSize)
+	return ((depth+skipPhase[i])/skipSize[i])%2 != 0
 }
 
-func (t *thread) iterativeDeepening(moves []EvaledMove, resultChan chan result, idx int) {
+func (t *thread) iterativeDeepening(moves []EvaledMove, idx int, report func(result)) {
 	var res result
 	mainThread := idx == 0
 	lastValue := -Mate
 	// I do not think this matters much, but at the beginning only thread with id 0 have sorted moves list
 	if !mainThread {
-		rand.Shuffle(len(moves), func(i, j int) {
+		t.rand.Shuffle(len(moves), func(i, j int) {
 			moves[i], moves[j] = moves[j], moves[i]
 		})
 	}
 
-	for depth := 1; depth <= MAX_HEIGHT; depth++ {
+	for depth := 1; depth <= MAX_HEIGHT && !t.engine.stopped(); depth++ {
+		if skipDepth(idx, depth) {
+			continue
+		}
 		res = t.aspirationWindow(depth, lastValue, moves)
-		resultChan <- res
+		if t.engine.stopped() {
+			return
+		}
+		t.completed = res
+		// Reported node count and soft timeout include all nodes of this thread
+		t.publishNodes()
+		report(res)
 		lastValue = res.value
 	}
 }
 
-func (e *Engine) bestMove(ctx context.Context, pos *Position) Move {
+// bestThread chooses result of a thread by votes weighted by depth and score, as in Stockfish.
+// Moves found by more threads at higher depths win even if they are not the ones that reached the highest depth.
+func (e *Engine) bestThread() *result {
+	best := &e.threads[0].completed
+	if len(e.threads) == 1 {
+		return best
+	}
+	minValue := Mate
+	for i := range e.threads {
+		if e.threads[i].completed.Move != NullMove {
+			minValue = Min(minValue, e.threads[i].completed.value)
+		}
+	}
+	votes := make(map[Move]int)
+	for i := range e.threads {
+		res := &e.threads[i].completed
+		if res.Move != NullMove {
+			votes[res.Move] += (res.value - minValue + 14) * res.depth
+		}
+	}
+	for i := range e.threads {
+		res := &e.threads[i].completed
+		if res.Move == NullMove {
+			continue
+		}
+		if best.Move == NullMove {
+			best = res
+		} else if best.value >= ValueWin {
+			// Prefer the shortest mate
+			if res.value > best.value {
+				best = res
+			}
+		} else if res.value >= ValueWin || res.value > ValueLoss && votes[res.Move] > votes[best.Move] {
+			best = res
+		}
+	}
+	return best
+}
+
+// attachAccumulators makes positions in stack use accumulators of thread, accumulator of root is computed from scratch
+func (t *thread) attachAccumulators() {
+	for height := range t.stack {
+		t.stack[height].position.Accumulator = &t.accumulators[height]
+	}
+	t.accumulators[0].Invalidate()
+}
+
+func (e *Engine) bestMove(pos *Position) result {
 	for i := range e.threads {
 		e.threads[i].stack[0].position = *pos
+		e.threads[i].attachAccumulators()
 		e.threads[i].nodes = 0
+		e.threads[i].publishNodes()
+		e.threads[i].completed = result{}
 	}
 
 	rootMoves := GenerateAllLegalMoves(pos)
 
-	if fathom.IsDTZProbeable(pos) {
-		if ok, bestMove, wdl, dtz := fathom.ProbeDTZ(pos, rootMoves); ok {
-			var score int
-			if wdl == fathom.TB_LOSS {
-				score = ValueLoss + dtz + 1
-			} else if wdl == fathom.TB_WIN {
-				score = ValueWin - dtz - 1
-			} else {
-				score = 0
-			}
-			e.Update(SearchInfo{newUciScore(score), MAX_HEIGHT - 1, 0, 1, 0, []Move{bestMove}})
-			return bestMove
-		}
-	}
+	rootMoves = e.rankRootMoves(pos, rootMoves)
+	e.rootMaterial = WDLMaterial(pos)
 
 	ordMove := NullMove
-	if hashOk, _, _, _, hashMove, _ := transposition.GlobalTransTable.Get(pos.Key); hashOk {
+	if hashOk, _, _, _, hashMove, _ := e.transTable.Get(pos.Key); hashOk {
 		ordMove = hashMove
 	}
 	e.threads[0].EvaluateMoves(pos, rootMoves, ordMove, 0, 127)
 
 	sortMoves(rootMoves)
 
-	if e.Threads.Val == 1 {
-		return e.singleThreadBestMove(ctx, rootMoves)
-	}
-
-	resultChan := make(chan result)
-	for i := range e.threads {
-		go func(idx int) {
-			defer recoverFromTimeout()
-			e.threads[idx].iterativeDeepening(cloneEvaledMoves(rootMoves), resultChan, idx)
-		}(i)
+	if s := e.skill(); s.enabled() && len(rootMoves) > 1 {
+		return e.skillBestMove(rootMoves, s)
 	}
 
+	var mu sync.Mutex
 	prevDepth := 0
-	var lastBestMove Move
-	for {
-		select {
-		case <-e.done:
-			// Hard timeout
-			return lastBestMove
-		case res := <-resultChan:
-			// If thread reports result for depth that is lower than already calculated one, ignore results
-			if res.depth <= prevDepth {
-				continue
-			}
-			nodes := e.nodes()
-			timeSinceStart := e.getElapsedTime()
-			e.Update(SearchInfo{newUciScore(res.value), res.depth, nodes, int(float64(nodes) / timeSinceStart.Seconds()), int(timeSinceStart.Milliseconds()), res.moves})
-			if res.value >= ValueWin && depthToMate(res.value) <= res.depth {
-				return res.Move
-			}
-			if res.Move == 0 {
-				return lastBestMove
-			}
-			if res.depth >= MAX_HEIGHT {
-				return res.Move
-			}
+	var reported result
+	// Results are reported on goroutines of threads, so iteration of thread waits for decision whether to stop
+	report := func(res result) {
+		mu.Lock()
+		defer mu.Unlock()
+		// If thread reports result for depth that is lower than already calculated one, ignore results
+		if e.stopped() || res.depth <= prevDepth {
+			return
+		}
+		e.reportResult(res)
+		reported = res
+		stop := false
+		if res.value >= ValueWin && depthToMate(res.value) <= res.depth || res.depth >= MAX_HEIGHT || res.Move == NullMove {
+			stop = true
+		} else {
 			e.updateTime(res.depth, res.value)
-			if e.isSoftTimeout(res.depth, nodes) {
-				return res.Move
-			}
-			lastBestMove = res.Move
-			prevDepth = res.depth
+			stop = e.isSoftTimeout(res.depth, e.nodes())
+		}
+		if stop {
+			e.stopSearch()
 		}
+		prevDepth = res.depth
 	}
+	e.runThreads(len(e.threads), func(t *thread, idx int) {
+		t.iterativeDeepening(cloneEvaledMoves(rootMoves), idx, report)
+	}).Wait()
+
+	// On hard timeout results of the last completed iterations are used
+	best := e.bestThread()
+	if best.Move == NullMove {
+		if len(rootMoves) > 0 {
+			return result{Move: rootMoves[0].Move}
+		}
+		return result{Move: NullMove}
+	}
+	if best.Move != reported.Move {
+		e.reportResult(*best)
+	}
+	return *best
 }
 
 func cloneMoves(src []Move) []Move {
@@ -844,13 +997,6 @@ func cloneEvaledMoves(src []EvaledMove) []EvaledMove {
 	return dst
 }
 
-func recoverFromTimeout() {
-	err := recover()
-	if err != nil && err != errTimeout {
-		panic(err)
-	}
-}
-
 // Gaps from Best Increments for the Average Case of Shellsort, Marcin Ciura.
 var shellSortGaps = [...]int{23, 10, 4, 1}
 
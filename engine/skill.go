@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+
+	. "github.com/mhib/combusken/backend"
+	. "github.com/mhib/combusken/utils"
+)
+
+// Playing strength is limited by capping search depth and nodes
+// and by choosing randomly among the best root moves found by MultiPV search.
+// The lower the level, the bigger score loss is accepted.
+
+const MaxSkillLevel = 20
+
+// Number of root moves considered by limited strength search
+const skillCandidates = 4
+
+// Elo of skill level is skillAnchorElo - skillEloSlope * (MaxSkillLevel - level),
+// so full strength has Elo of the anchor. Slope is fitted by `combusken calibrate 40 50 2800 2`:
+//
+//	Level 18 scored 2.5/40 against level 20, Elo: 2330
+//	Level 16 scored 11.5/40 against level 18, Elo: 2172
+//	Level 14 scored 10.0/40 against level 16, Elo: 1981
+//	Level 12 scored 7.5/40 against level 14, Elo: 1726
+//	Level 10 scored 8.5/40 against level 12, Elo: 1499
+//	Level 8 scored 7.5/40 against level 10, Elo: 1244
+//	Level 6 scored 2.0/40 against level 8, Elo: 733
+//	Level 4 scored 7.0/40 against level 6, Elo: 463
+//	Level 2 scored 9.5/40 against level 4, Elo: 261
+//	Level 0 scored 3.5/40 against level 2, Elo: -147
+//	Elo = 2800 - 142.6 * (20 - level)
+const skillAnchorElo = 2800
+const skillEloSlope = 143
+
+const MinElo = skillAnchorElo - skillEloSlope*MaxSkillLevel
+const MaxElo = skillAnchorElo
+
+type skill struct {
+	level float64
+}
+
+func (e *Engine) skill() skill {
+	if e.LimitStrength.Val {
+		return skill{MaxSkillLevel - float64(skillAnchorElo-e.Elo.Val)/skillEloSlope}
+	}
+	return skill{float64(e.SkillLevel.Val)}
+}
+
+func (s skill) enabled() bool {
+	return s.level < MaxSkillLevel
+}
+
+func (s skill) maxDepth() int {
+	return 1 + int(s.level)
+}
+
+func (s skill) maxNodes() int {
+	return int(200 * math.Pow(1.6, s.level))
+}
+
+// temperature is score difference in centipawns that makes a move e times less likely to be chosen
+func (s skill) temperature() float64 {
+	return 5 + 10*(MaxSkillLevel-s.level)
+}
+
+// pick chooses one of the candidates sorted by score, probability of a move decreases exponentially with its score loss
+func (s skill) pick(candidates []result, rng *rand.Rand) result {
+	weights := make([]float64, len(candidates))
+	sum := 0.0
+	for i := range candidates {
+		weights[i] = math.Exp(float64(candidates[i].value-candidates[0].value) / s.temperature())
+		sum += weights[i]
+	}
+	choice := rng.Float64() * sum
+	for i := range candidates {
+		choice -= weights[i]
+		if choice < 0 {
+			return candidates[i]
+		}
+	}
+	return candidates[0]
+}
+
+// multiPV returns results of the best root moves sorted by score
+func (t *thread) multiPV(depth, lastValue int, moves []EvaledMove) (res []result) {
+	t.updateContempt(depth, lastValue)
+	remaining := cloneEvaledMoves(moves)
+	for len(res) < skillCandidates && len(remaining) > 0 {
+		r := t.depSearch(depth, -Mate, Mate, remaining)
+		if r.Move == NullMove {
+			break
+		}
+		res = append(res, r)
+		for i := range remaining {
+			if remaining[i].Move == r.Move {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return
+}
+
+// skillBestMove searches with a single thread within limits of skill and picks one of the best moves
+func (e *Engine) skillBestMove(rootMoves []EvaledMove, s skill) result {
+	var candidates []result
+	// Iteration that exceeds node cap is abandoned, and candidates of the previous one are used
+	if e.nodeLimit == 0 || s.maxNodes() < e.nodeLimit {
+		e.nodeLimit = s.maxNodes()
+	}
+	e.runThreads(1, func(t *thread, _ int) {
+		lastValue := -Mate
+		for depth := 1; depth <= s.maxDepth(); depth++ {
+			res := t.multiPV(depth, lastValue, rootMoves)
+			if t.engine.stopped() || len(res) == 0 {
+				return
+			}
+			candidates = res
+			lastValue = res[0].value
+			t.publishNodes()
+			e.reportResult(res[0])
+			if e.isSoftTimeout(depth, t.nodes) {
+				return
+			}
+		}
+	}).Wait()
+	if len(candidates) == 0 {
+		return result{Move: rootMoves[0].Move}
+	}
+	// The last reported line has to match the played move
+	picked := s.pick(candidates, e.skillRand)
+	if picked.Move != candidates[0].Move {
+		e.reportResult(picked)
+	}
+	return picked
+}
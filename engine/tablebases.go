@@ -0,0 +1,116 @@
+package engine
+
+import (
+	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/fathom"
+	. "github.com/mhib/combusken/utils"
+)
+
+// Syzygy tablebases are probed first, tables generated by tablebase package
+// are used when Syzygy is unavailable or does not cover the position.
+// Both packages use the same result constants.
+// Syzygy tables are loaded by fathom into global state of the process,
+// generated tables are held by every engine separately.
+
+func (e *Engine) probeWDL(pos *Position, depth int) int64 {
+	if fathom.IsWDLProbeable(pos, depth, e.SyzygyProbeDepth.Val) {
+		if res := fathom.ProbeWDL(pos, depth); res != fathom.TB_RESULT_FAILED {
+			return res
+		}
+	}
+	if e.tablebase.IsWDLProbeable(pos, depth, e.SyzygyProbeDepth.Val) {
+		return e.tablebase.ProbeWDL(pos, depth)
+	}
+	return fathom.TB_RESULT_FAILED
+}
+
+// tablebaseRoot holds result of ranking root moves with tablebases
+type tablebaseRoot struct {
+	// Root position is in tablebases and root moves are restricted to the best ranked ones
+	inTablebase bool
+	// Score of the best ranked moves, reported instead of search score unless search finds a mate
+	score int
+	// Tablebases are probed in search only if root moves are not ranked by distance
+	probeInSearch bool
+}
+
+// rankRootMoves returns root moves that preserve the best tablebase result
+func (e *Engine) rankRootMoves(pos *Position, moves []EvaledMove) []EvaledMove {
+	e.tablebaseRoot = tablebaseRoot{probeInSearch: true}
+	if len(moves) == 0 {
+		return moves
+	}
+	ranks := make([]int, len(moves))
+	scores := make([]int, len(moves))
+	var ok, byDistance bool
+	if fathom.IsDTZProbeable(pos) {
+		ok, byDistance = fathom.ProbeRoot(pos, moves, e.hasRepeated(pos), e.Syzygy50MoveRule.Val, ranks, scores)
+		if ok {
+			dtz := 0
+			if found, _, _, distance := fathom.ProbeDTZ(pos, moves); found {
+				dtz = distance
+			}
+			for i := range scores {
+				scores[i] = syzygyScore(scores[i], dtz)
+			}
+		}
+	}
+	if !ok && e.tablebase.IsDTZProbeable(pos) {
+		ok = e.tablebase.ProbeRoot(pos, moves, ranks, scores)
+		byDistance = ok
+	}
+	if !ok {
+		return moves
+	}
+
+	best := 0
+	for i := range ranks {
+		if ranks[i] > ranks[best] {
+			best = i
+		}
+	}
+	e.tablebaseRoot = tablebaseRoot{
+		inTablebase:   true,
+		score:         scores[best],
+		probeInSearch: !byDistance && scores[best] > 0,
+	}
+	var filtered []EvaledMove
+	for i := range moves {
+		if ranks[i] == ranks[best] {
+			filtered = append(filtered, moves[i])
+		}
+	}
+	return filtered
+}
+
+// syzygyScore maps score of Syzygy root move to range of tablebase results in search,
+// so wins are reported like the ones found by probes in search
+func syzygyScore(score, dtz int) int {
+	if score >= fathom.TB_WIN_SCORE {
+		return ValueWin - 1 - Min(dtz, MAX_HEIGHT)
+	} else if score <= -fathom.TB_WIN_SCORE {
+		return ValueLoss + 1 + Min(dtz, MAX_HEIGHT)
+	}
+	return score
+}
+
+// hasRepeated returns true if any position repeated since the last irreversible move
+func (e *Engine) hasRepeated(pos *Position) bool {
+	keys := append(append([]uint64{}, e.history...), pos.Key)
+	for i := len(keys) - 1; i >= 0; i-- {
+		for j := i - 2; j >= 0; j -= 2 {
+			if keys[i] == keys[j] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reportedScore replaces search score with tablebase score when root is in tablebases
+func (e *Engine) reportedScore(value int) int {
+	if e.tablebaseRoot.inTablebase && value < ValueWin && value > ValueLoss {
+		return e.tablebaseRoot.score
+	}
+	return value
+}
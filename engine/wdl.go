@@ -0,0 +1,19 @@
+package engine
+
+import "github.com/mhib/combusken/evaluation"
+
+// WDLScore holds expected win, draw and loss rates in permille from side to move perspective
+type WDLScore struct {
+	Win  int
+	Draw int
+	Loss int
+}
+
+// wdlScore converts search value to win, draw and loss rates using material of the root position
+func (e *Engine) wdlScore(value int) WDLScore {
+	if !e.ShowWDL.Val {
+		return WDLScore{}
+	}
+	win, draw, loss := evaluation.DefaultWDLModel.WDL(e.reportedScore(value), e.rootMaterial)
+	return WDLScore{win, draw, loss}
+}
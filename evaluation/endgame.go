@@ -0,0 +1,202 @@
+package evaluation
+
+import (
+	"strings"
+
+	. "github.com/mhib/combusken/backend"
+	. "github.com/mhib/combusken/utils"
+)
+
+// Endgames with known outcome are recognised by material signature
+// and evaluated or scaled by dedicated functions instead of the general evaluation.
+
+// KnownWin is added to evaluation of endgames that are won for sure
+const KnownWin = 10000
+
+const scaleUnknown = -1
+
+// endgameFunc returns either score from the strong side perspective or scale factor
+type endgameFunc func(pos *Position, strongSide int) int
+
+type endgame struct {
+	strongSide int
+	evaluate   endgameFunc
+	scale      endgameFunc
+}
+
+var endgames = make(map[uint64]endgame)
+
+var pushAway = [8]int{0, 5, 20, 40, 60, 80, 90, 100}
+
+func init() {
+	addEndgame("KBNK", evaluateKBNK, nil)
+	addEndgame("KRKP", evaluateKRKP, nil)
+	addEndgame("KQKP", evaluateKQKP, nil)
+	addEndgame("KRKB", evaluateKRKB, nil)
+	addEndgame("KRKN", evaluateKRKN, nil)
+	addEndgame("KNNK", evaluateKNNK, nil)
+	addEndgame("KPK", evaluateKPK, nil)
+	for pawns := 1; pawns <= 8; pawns++ {
+		addEndgame("KB"+strings.Repeat("P", pawns)+"K", nil, scaleKBPsK)
+	}
+}
+
+// materialKey packs number of every piece type of both sides, kings excluded
+func materialKey(pos *Position) (key uint64) {
+	for side := Black; side <= White; side++ {
+		for piece := Pawn; piece <= Queen; piece++ {
+			key |= uint64(PopCount(pos.Colours[side]&pos.Pieces[piece])) << uint(4*(side*(Queen+1)+piece))
+		}
+	}
+	return
+}
+
+// signatureKey converts signature like "KBNK" to material key.
+// Pieces before the last king belong to the strong side.
+func signatureKey(code string, strongSide int) (key uint64) {
+	weakStart := strings.LastIndex(code, "K")
+	for i, letter := range code {
+		piece := strings.IndexRune("PNBRQ", letter)
+		if piece < 0 {
+			continue
+		}
+		side := strongSide
+		if i > weakStart {
+			side ^= 1
+		}
+		key += 1 << uint(4*(side*(Queen+1)+piece))
+	}
+	return
+}
+
+func addEndgame(code string, evaluate, scale endgameFunc) {
+	for strongSide := Black; strongSide <= White; strongSide++ {
+		endgames[signatureKey(code, strongSide)] = endgame{strongSide, evaluate, scale}
+	}
+}
+
+func probeEndgame(pos *Position) (endgame, bool) {
+	// Every registered endgame has at most two pieces other than kings and pawns
+	if tuning || PopCount(pos.Pieces[Knight]|pos.Pieces[Bishop]|pos.Pieces[Rook]|pos.Pieces[Queen]) > 2 {
+		return endgame{}, false
+	}
+	eg, ok := endgames[materialKey(pos)]
+	return eg, ok
+}
+
+// evaluateRelative returns endgame evaluation from side to move perspective
+func (eg *endgame) evaluateRelative(pos *Position) int {
+	result := eg.evaluate(pos, eg.strongSide)
+	if pos.SideToMove == eg.strongSide {
+		return result
+	}
+	return -result
+}
+
+func relativeSquare(side, square int) int {
+	if side == White {
+		return square
+	}
+	return square ^ 56
+}
+
+func distance(from, to int) int {
+	return int(distanceBetween[from][to])
+}
+
+// pushToEdge is bigger the closer square is to the edge of the board
+func pushToEdge(square int) int {
+	centerDistance := Max(FILE_D-File(square), File(square)-FILE_E) + Max(RANK_4-Rank(square), Rank(square)-RANK_5)
+	return 15 * centerDistance
+}
+
+// pushClose is bigger the closer squares are
+func pushClose(from, to int) int {
+	return 140 - 20*distance(from, to)
+}
+
+func kingSquare(pos *Position, side int) int {
+	return BitScan(pos.Pieces[King] & pos.Colours[side])
+}
+
+// Bishop and knight mate is forced by driving weak king to the corner of bishop's colour
+func evaluateKBNK(pos *Position, strongSide int) int {
+	strongKing := kingSquare(pos, strongSide)
+	weakKing := kingSquare(pos, strongSide^1)
+	cornerDistance := Min(distance(weakKing, A1), distance(weakKing, H8))
+	if pos.Pieces[Bishop]&WHITE_SQUARES != 0 {
+		cornerDistance = Min(distance(weakKing, A8), distance(weakKing, H1))
+	}
+	return KnownWin + int(BishopValue.End()+KnightValue.End()) + pushClose(strongKing, weakKing) + 30*(7-cornerDistance)
+}
+
+// Rook against pawn is a win unless pawn is far advanced and supported by its king
+func evaluateKRKP(pos *Position, strongSide int) int {
+	strongKing := relativeSquare(strongSide, kingSquare(pos, strongSide))
+	weakKing := relativeSquare(strongSide, kingSquare(pos, strongSide^1))
+	rook := relativeSquare(strongSide, BitScan(pos.Pieces[Rook]))
+	pawn := relativeSquare(strongSide, BitScan(pos.Pieces[Pawn]))
+	queeningSquare := File(pawn)
+	weakToMove := BoolToInt(pos.SideToMove != strongSide)
+
+	// Strong king stands in front of the pawn
+	if forwardFileMask[White][strongKing]&SquareMask[pawn] != 0 {
+		return int(RookValue.End()) - distance(strongKing, pawn)
+	}
+	// Weak king is too far from both pawn and rook
+	if distance(weakKing, pawn) >= 3+weakToMove && distance(weakKing, rook) >= 3 {
+		return int(RookValue.End()) - distance(strongKing, pawn)
+	}
+	// Pawn is far advanced, supported by the king and strong king is far away
+	if Rank(weakKing) <= RANK_3 && distance(weakKing, pawn) == 1 && Rank(strongKing) >= RANK_4 &&
+		distance(strongKing, pawn) > 3-weakToMove {
+		return 80 - 8*distance(strongKing, pawn)
+	}
+	return 200 - 8*(distance(strongKing, pawn-8)-distance(weakKing, pawn-8)-distance(pawn, queeningSquare))
+}
+
+// Queen against pawn is a win unless rook or bishop pawn on seventh rank is supported by its king
+func evaluateKQKP(pos *Position, strongSide int) int {
+	strongKing := kingSquare(pos, strongSide)
+	weakKing := kingSquare(pos, strongSide^1)
+	pawn := BitScan(pos.Pieces[Pawn])
+	result := pushClose(strongKing, pawn)
+	if Rank(relativeSquare(strongSide^1, pawn)) != RANK_7 || distance(weakKing, pawn) != 1 ||
+		(FILE_A_BB|FILE_C_BB|FILE_F_BB|FILE_H_BB)&SquareMask[pawn] == 0 {
+		result += int(QueenValue.End() - PawnValue.End())
+	}
+	return result
+}
+
+// Rook against bishop is usually a draw, weak king should stay away from the edge
+func evaluateKRKB(pos *Position, strongSide int) int {
+	return pushToEdge(kingSquare(pos, strongSide^1))
+}
+
+// Rook against knight is usually a draw, weak king should stay close to its knight and away from the edge
+func evaluateKRKN(pos *Position, strongSide int) int {
+	weakKing := kingSquare(pos, strongSide^1)
+	knight := BitScan(pos.Pieces[Knight])
+	return pushToEdge(weakKing) + pushAway[distance(weakKing, knight)]
+}
+
+// Two knights cannot force mate
+func evaluateKNNK(pos *Position, strongSide int) int {
+	return 0
+}
+
+// Rook pawns with bishop that does not control promotion square
+// cannot win when weak king reaches the corner
+func scaleKBPsK(pos *Position, strongSide int) int {
+	pawns := pos.Pieces[Pawn]
+	if pawns&^FILE_A_BB != 0 && pawns&^FILE_H_BB != 0 {
+		return scaleUnknown
+	}
+	queeningSquare := relativeSquare(strongSide, File(BitScan(pawns))+A8)
+	bishopOnWhite := pos.Pieces[Bishop]&WHITE_SQUARES != 0
+	queeningOnWhite := SquareMask[queeningSquare]&WHITE_SQUARES != 0
+	if bishopOnWhite != queeningOnWhite && distance(kingSquare(pos, strongSide^1), queeningSquare) <= 1 {
+		return SCALE_DRAW
+	}
+	return scaleUnknown
+}
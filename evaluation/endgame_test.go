@@ -0,0 +1,78 @@
+package evaluation
+
+import (
+	"testing"
+
+	. "github.com/mhib/combusken/backend"
+	. "github.com/mhib/combusken/utils"
+)
+
+const (
+	resultDraw = iota
+	resultWin
+)
+
+// Endgames with known outcome, side to move is the stronger side
+var endgameTests = []struct {
+	fen    string
+	result int
+}{
+	// KBNK
+	{"8/8/8/4k3/8/8/8/KBN5 w - - 0 1", resultWin},
+	{"kbn5/8/8/8/4K3/8/8/8 b - - 0 1", resultWin},
+	// KRKP, strong king in front of the pawn
+	{"8/8/8/8/3k4/8/3p4/3K3R w - - 0 1", resultWin},
+	// KRKP, advanced pawn supported by king
+	{"K6R/8/8/8/8/8/2kp4/8 w - - 0 1", resultDraw},
+	// KQKP, central pawn
+	{"K7/8/1Q6/8/8/8/3pk3/8 w - - 0 1", resultWin},
+	// KQKP, bishop pawn on seventh rank
+	{"K7/8/1Q6/8/8/8/5pk1/8 w - - 0 1", resultDraw},
+	{"8/5PK1/8/8/8/1q6/8/k7 b - - 0 1", resultDraw},
+	// KRKB
+	{"8/8/8/3k4/8/3b4/8/3RK3 w - - 0 1", resultDraw},
+	// KRKN
+	{"8/8/8/3k4/3n4/8/8/3RK3 w - - 0 1", resultDraw},
+	// KNNK
+	{"8/8/8/3k4/8/8/8/NNK5 w - - 0 1", resultDraw},
+	// Rook pawn with wrong bishop
+	{"1k6/8/8/8/P7/8/8/K1B5 w - - 0 1", resultDraw},
+	{"k4b2/8/8/8/7p/8/8/6K1 b - - 0 1", resultDraw},
+	// Rook pawn with right bishop
+	{"1k6/8/8/8/P7/8/8/K2B4 w - - 0 1", resultWin},
+}
+
+func TestEndgames(t *testing.T) {
+	table := NewPawnKingTable(1)
+	evaluator := Evaluator{PawnKingTable: &table}
+	for _, test := range endgameTests {
+		pos := ParseFen(test.fen)
+		if _, ok := probeEndgame(&pos); !ok {
+			t.Errorf("%s: endgame not recognised", test.fen)
+			continue
+		}
+		val := evaluator.Evaluate(&pos)
+		switch test.result {
+		case resultWin:
+			if val < 400 {
+				t.Errorf("%s: expected win, got %d", test.fen, val)
+			}
+		case resultDraw:
+			if Abs(val) > 100 {
+				t.Errorf("%s: expected draw, got %d", test.fen, val)
+			}
+		}
+	}
+}
+
+func TestKBNKDrivesToBishopCorner(t *testing.T) {
+	var evaluator Evaluator
+	rightCorner := ParseFen("k7/8/8/8/8/8/8/1BN1K3 w - - 0 1")
+	wrongCorner := ParseFen("8/8/8/8/8/8/8/kBN1K3 w - - 0 1")
+	if evaluator.Evaluate(&rightCorner) <= evaluator.Evaluate(&wrongCorner) {
+		t.Errorf("King in bishop's corner should be evaluated higher")
+	}
+	if evaluator.Evaluate(&rightCorner) < KnownWin {
+		t.Errorf("KBNK should be a known win")
+	}
+}
@@ -2,12 +2,22 @@ package evaluation
 
 import (
 	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/nnue"
 	. "github.com/mhib/combusken/utils"
 )
 
 const tuning = false
 
-var T Trace
+// Evaluator holds state used by evaluation.
+// It is not safe for concurrent use, so every search thread has its own one.
+type Evaluator struct {
+	// Evaluation of pawns and kings is not cached when it is nil
+	PawnKingTable *PawnKingTable
+	// Network used instead of classical evaluation when it is not nil
+	Network *nnue.Network
+	// Evaluation terms are counted when tuning
+	T Trace
+}
 
 const PawnPhase = 0
 const KnightPhase = 1
@@ -391,9 +401,9 @@ func IsLateEndGame(pos *Position) bool {
 	return ((pos.Pieces[Rook] | pos.Pieces[Queen] | pos.Pieces[Bishop] | pos.Pieces[Knight]) & pos.Colours[pos.SideToMove]) == 0
 }
 
-func evaluateKingPawns(pos *Position) Score {
-	if !tuning {
-		if ok, score := GlobalPawnKingTable.Get(pos.PawnKey); ok {
+func (e *Evaluator) evaluateKingPawns(pos *Position) Score {
+	if !tuning && e.PawnKingTable != nil {
+		if ok, score := e.PawnKingTable.Get(pos.PawnKey); ok {
 			return score
 		}
 	}
@@ -409,8 +419,8 @@ func evaluateKingPawns(pos *Position) Score {
 
 		score += Psqt[White][Pawn][fromId]
 		if tuning {
-			T.PawnValue++
-			T.PawnScores[Rank(fromId)][File(fromId)]++
+			e.T.PawnValue++
+			e.T.PawnScores[Rank(fromId)][File(fromId)]++
 		}
 
 		// Passed bonus
@@ -423,16 +433,16 @@ func evaluateKingPawns(pos *Position) Score {
 					PassedEnemyDistance[distanceBetween[blackKingLocation][fromId]]
 
 			if tuning {
-				T.PassedRank[Rank(fromId)]++
-				T.PassedFile[File(fromId)]++
-				T.PassedFriendlyDistance[distanceBetween[whiteKingLocation][fromId]]++
-				T.PassedEnemyDistance[distanceBetween[blackKingLocation][fromId]]++
+				e.T.PassedRank[Rank(fromId)]++
+				e.T.PassedFile[File(fromId)]++
+				e.T.PassedFriendlyDistance[distanceBetween[whiteKingLocation][fromId]]++
+				e.T.PassedEnemyDistance[distanceBetween[blackKingLocation][fromId]]++
 			}
 
 			if pos.Pieces[Pawn]&pos.Colours[White]&forwardFileMask[White][fromId] != 0 {
 				score += PassedStacked[Rank(fromId)]
 				if tuning {
-					T.PassedStacked[Rank(fromId)]++
+					e.T.PassedStacked[Rank(fromId)]++
 				}
 			}
 		}
@@ -441,7 +451,7 @@ func evaluateKingPawns(pos *Position) Score {
 		if adjacentFilesMask[File(fromId)]&(pos.Pieces[Pawn]&pos.Colours[White]) == 0 {
 			score += Isolated
 			if tuning {
-				T.Isolated++
+				e.T.Isolated++
 			}
 		}
 
@@ -451,18 +461,18 @@ func evaluateKingPawns(pos *Position) Score {
 			if FILES[File(fromId)]&(pos.Pieces[Pawn]&pos.Colours[Black]) == 0 {
 				score += BackwardOpen
 				if tuning {
-					T.BackwardOpen++
+					e.T.BackwardOpen++
 				}
 			} else {
 				score += Backward
 				if tuning {
-					T.Backward++
+					e.T.Backward++
 				}
 			}
 		} else if pawnsConnectedMask[White][fromId]&(pos.Colours[White]&pos.Pieces[Pawn]) != 0 {
 			score += PawnsConnectedSquare[White][fromId]
 			if tuning {
-				T.PawnsConnected[Rank(fromId)][FileMirror[File(fromId)]]++
+				e.T.PawnsConnected[Rank(fromId)][FileMirror[File(fromId)]]++
 			}
 		}
 	}
@@ -470,7 +480,7 @@ func evaluateKingPawns(pos *Position) Score {
 	// white doubled pawns
 	score += Score(PopCount(pos.Pieces[Pawn]&pos.Colours[White]&South(pos.Pieces[Pawn]&pos.Colours[White]))) * Doubled
 	if tuning {
-		T.Doubled += PopCount(pos.Pieces[Pawn] & pos.Colours[White] & South(pos.Pieces[Pawn]&pos.Colours[White]))
+		e.T.Doubled += PopCount(pos.Pieces[Pawn] & pos.Colours[White] & South(pos.Pieces[Pawn]&pos.Colours[White]))
 	}
 
 	// black pawns
@@ -480,8 +490,8 @@ func evaluateKingPawns(pos *Position) Score {
 		score -= Psqt[Black][Pawn][fromId]
 
 		if tuning {
-			T.PawnValue--
-			T.PawnScores[7-Rank(fromId)][File(fromId)]--
+			e.T.PawnValue--
+			e.T.PawnScores[7-Rank(fromId)][File(fromId)]--
 		}
 		if passedMask[Black][fromId]&(pos.Pieces[Pawn]&pos.Colours[White]) == 0 {
 			score -=
@@ -490,23 +500,23 @@ func evaluateKingPawns(pos *Position) Score {
 					PassedFriendlyDistance[distanceBetween[blackKingLocation][fromId]] +
 					PassedEnemyDistance[distanceBetween[whiteKingLocation][fromId]]
 			if tuning {
-				T.PassedRank[7-Rank(fromId)]--
-				T.PassedFile[File(fromId)]--
-				T.PassedFriendlyDistance[distanceBetween[blackKingLocation][fromId]]--
-				T.PassedEnemyDistance[distanceBetween[whiteKingLocation][fromId]]--
+				e.T.PassedRank[7-Rank(fromId)]--
+				e.T.PassedFile[File(fromId)]--
+				e.T.PassedFriendlyDistance[distanceBetween[blackKingLocation][fromId]]--
+				e.T.PassedEnemyDistance[distanceBetween[whiteKingLocation][fromId]]--
 			}
 
 			if pos.Pieces[Pawn]&pos.Colours[Black]&forwardFileMask[Black][fromId] != 0 {
 				score -= PassedStacked[7-Rank(fromId)]
 				if tuning {
-					T.PassedStacked[7-Rank(fromId)]--
+					e.T.PassedStacked[7-Rank(fromId)]--
 				}
 			}
 		}
 		if adjacentFilesMask[File(fromId)]&(pos.Pieces[Pawn]&pos.Colours[Black]) == 0 {
 			score -= Isolated
 			if tuning {
-				T.Isolated--
+				e.T.Isolated--
 			}
 		}
 		if passedMask[White][fromId]&(pos.Pieces[Pawn]&pos.Colours[Black]) == 0 &&
@@ -514,18 +524,18 @@ func evaluateKingPawns(pos *Position) Score {
 			if FILES[File(fromId)]&(pos.Pieces[Pawn]&pos.Colours[White]) == 0 {
 				score -= BackwardOpen
 				if tuning {
-					T.BackwardOpen--
+					e.T.BackwardOpen--
 				}
 			} else {
 				score -= Backward
 				if tuning {
-					T.Backward--
+					e.T.Backward--
 				}
 			}
 		} else if pawnsConnectedMask[Black][fromId]&(pos.Colours[Black]&pos.Pieces[Pawn]) != 0 {
 			score -= PawnsConnectedSquare[Black][fromId]
 			if tuning {
-				T.PawnsConnected[7-Rank(fromId)][FileMirror[File(fromId)]]--
+				e.T.PawnsConnected[7-Rank(fromId)][FileMirror[File(fromId)]]--
 			}
 		}
 	}
@@ -533,7 +543,7 @@ func evaluateKingPawns(pos *Position) Score {
 	// black doubled pawns
 	score -= Score(PopCount(pos.Pieces[Pawn]&pos.Colours[Black]&North(pos.Pieces[Pawn]&pos.Colours[Black]))) * Doubled
 	if tuning {
-		T.Doubled -= PopCount(pos.Pieces[Pawn] & pos.Colours[Black] & North(pos.Pieces[Pawn]&pos.Colours[Black]))
+		e.T.Doubled -= PopCount(pos.Pieces[Pawn] & pos.Colours[Black] & North(pos.Pieces[Pawn]&pos.Colours[Black]))
 	}
 
 	// White king storm shelter
@@ -555,14 +565,14 @@ func evaluateKingPawns(pos *Position) Score {
 		sameFile := BoolToInt(file == File(whiteKingLocation))
 		score += KingShelter[sameFile][file][ourDist]
 		if tuning {
-			T.KingShelter[sameFile][file][ourDist]++
+			e.T.KingShelter[sameFile][file][ourDist]++
 		}
 
 		blocked := BoolToInt(ourDist != 7 && ourDist == theirDist-1)
 		score += KingStorm[blocked][FileMirror[file]][theirDist]
 
 		if tuning {
-			T.KingStorm[blocked][FileMirror[file]][theirDist]++
+			e.T.KingStorm[blocked][FileMirror[file]][theirDist]++
 		}
 	}
 
@@ -585,22 +595,22 @@ func evaluateKingPawns(pos *Position) Score {
 		sameFile := BoolToInt(file == File(blackKingLocation))
 		score -= KingShelter[sameFile][file][ourDist]
 		if tuning {
-			T.KingShelter[sameFile][file][ourDist]--
+			e.T.KingShelter[sameFile][file][ourDist]--
 		}
 
 		blocked := BoolToInt(ourDist != 7 && ourDist == theirDist-1)
 		score -= KingStorm[blocked][FileMirror[file]][theirDist]
 		if tuning {
-			T.KingStorm[blocked][FileMirror[file]][theirDist]--
+			e.T.KingStorm[blocked][FileMirror[file]][theirDist]--
 		}
 	}
-	if !tuning {
-		GlobalPawnKingTable.Set(pos.PawnKey, score)
+	if !tuning && e.PawnKingTable != nil {
+		e.PawnKingTable.Set(pos.PawnKey, score)
 	}
 	return score
 }
 
-func Evaluate(pos *Position) int {
+func (e *Evaluator) Evaluate(pos *Position) int {
 	var fromId int
 	var fromBB uint64
 	var attacks uint64
@@ -618,6 +628,14 @@ func Evaluate(pos *Position) int {
 	var blackKingAttackersCount int16
 	var blackKingAttackersWeight int16
 
+	eg, hasEndgame := probeEndgame(pos)
+	if hasEndgame && eg.evaluate != nil {
+		return eg.evaluateRelative(pos)
+	}
+	if e.Network != nil {
+		return evaluateNetwork(pos, e.Network, eg, hasEndgame)
+	}
+
 	phase := TotalPhase
 	whiteMobilityArea := ^((pos.Pieces[Pawn] & pos.Colours[White]) | (BlackPawnsAttacks(pos.Pieces[Pawn] & pos.Colours[Black])))
 	blackMobilityArea := ^((pos.Pieces[Pawn] & pos.Colours[Black]) | (WhitePawnsAttacks(pos.Pieces[Pawn] & pos.Colours[White])))
@@ -649,7 +667,7 @@ func Evaluate(pos *Position) int {
 	blackAttackedBy[Pawn] |= attacks
 	blackKingAttacksCount += int16(PopCount(attacks & whiteKingArea))
 
-	score := evaluateKingPawns(pos)
+	score := e.evaluateKingPawns(pos)
 
 	// white knights
 	for fromBB = pos.Pieces[Knight] & pos.Colours[White]; fromBB != 0; fromBB &= (fromBB - 1) {
@@ -661,9 +679,9 @@ func Evaluate(pos *Position) int {
 		score += Psqt[White][Knight][fromId]
 		score += MobilityBonus[0][mobility]
 		if tuning {
-			T.KnightValue++
-			T.PieceScores[Knight][Rank(fromId)][FileMirror[File(fromId)]]++
-			T.MobilityBonus[0][mobility]++
+			e.T.KnightValue++
+			e.T.PieceScores[Knight][Rank(fromId)][FileMirror[File(fromId)]]++
+			e.T.MobilityBonus[0][mobility]++
 		}
 
 		whiteAttackedByTwo |= whiteAttacked & attacks
@@ -673,19 +691,19 @@ func Evaluate(pos *Position) int {
 		if (pos.Pieces[Pawn]>>8)&SquareMask[fromId] != 0 {
 			score += MinorBehindPawn
 			if tuning {
-				T.MinorBehindPawn++
+				e.T.MinorBehindPawn++
 			}
 		}
 		if SquareMask[fromId]&whiteOutpustRanks != 0 && outpustMask[White][fromId]&(pos.Pieces[Pawn]&pos.Colours[Black]) == 0 {
 			if PawnAttacks[Black][fromId]&(pos.Pieces[Pawn]&pos.Colours[White]) != 0 {
 				score += KnightOutpostDefendedBonus
 				if tuning {
-					T.KnightOutpostDefendedBonus++
+					e.T.KnightOutpostDefendedBonus++
 				}
 			} else {
 				score += KnightOutpostUndefendedBonus
 				if tuning {
-					T.KnightOutpostUndefendedBonus++
+					e.T.KnightOutpostUndefendedBonus++
 				}
 			}
 		}
@@ -694,7 +712,7 @@ func Evaluate(pos *Position) int {
 		if kingDistance >= 4 {
 			score += DistantKnight[kingDistance-4]
 			if tuning {
-				T.DistantKnight[kingDistance-4]++
+				e.T.DistantKnight[kingDistance-4]++
 			}
 		}
 		if attacks&blackKingArea != 0 {
@@ -714,9 +732,9 @@ func Evaluate(pos *Position) int {
 		score -= Psqt[Black][Knight][fromId]
 		score -= MobilityBonus[0][mobility]
 		if tuning {
-			T.KnightValue--
-			T.PieceScores[Knight][7-Rank(fromId)][FileMirror[File(fromId)]]--
-			T.MobilityBonus[0][mobility]--
+			e.T.KnightValue--
+			e.T.PieceScores[Knight][7-Rank(fromId)][FileMirror[File(fromId)]]--
+			e.T.MobilityBonus[0][mobility]--
 		}
 
 		blackAttackedByTwo |= blackAttacked & attacks
@@ -726,19 +744,19 @@ func Evaluate(pos *Position) int {
 		if (pos.Pieces[Pawn]<<8)&SquareMask[fromId] != 0 {
 			score -= MinorBehindPawn
 			if tuning {
-				T.MinorBehindPawn--
+				e.T.MinorBehindPawn--
 			}
 		}
 		if SquareMask[fromId]&blackOutpustRanks != 0 && outpustMask[Black][fromId]&(pos.Pieces[Pawn]&pos.Colours[White]) == 0 {
 			if PawnAttacks[White][fromId]&(pos.Pieces[Pawn]&pos.Colours[Black]) != 0 {
 				score -= KnightOutpostDefendedBonus
 				if tuning {
-					T.KnightOutpostDefendedBonus--
+					e.T.KnightOutpostDefendedBonus--
 				}
 			} else {
 				score -= KnightOutpostUndefendedBonus
 				if tuning {
-					T.KnightOutpostUndefendedBonus--
+					e.T.KnightOutpostUndefendedBonus--
 				}
 			}
 		}
@@ -746,7 +764,7 @@ func Evaluate(pos *Position) int {
 		if kingDistance >= 4 {
 			score -= DistantKnight[kingDistance-4]
 			if tuning {
-				T.DistantKnight[kingDistance-4]--
+				e.T.DistantKnight[kingDistance-4]--
 			}
 		}
 		if attacks&whiteKingArea != 0 {
@@ -767,9 +785,9 @@ func Evaluate(pos *Position) int {
 		score += MobilityBonus[1][mobility]
 		score += Psqt[White][Bishop][fromId]
 		if tuning {
-			T.BishopValue++
-			T.PieceScores[Bishop][Rank(fromId)][FileMirror[File(fromId)]]++
-			T.MobilityBonus[1][mobility]++
+			e.T.BishopValue++
+			e.T.PieceScores[Bishop][Rank(fromId)][FileMirror[File(fromId)]]++
+			e.T.MobilityBonus[1][mobility]++
 		}
 
 		whiteAttackedByTwo |= whiteAttacked & attacks
@@ -779,25 +797,25 @@ func Evaluate(pos *Position) int {
 		if (pos.Pieces[Pawn]>>8)&SquareMask[fromId] != 0 {
 			score += MinorBehindPawn
 			if tuning {
-				T.MinorBehindPawn++
+				e.T.MinorBehindPawn++
 			}
 		}
 		if (LONG_DIAGONALS&SquareMask[fromId]) != 0 && (MoreThanOne(BishopAttacks(fromId, pos.Pieces[Pawn]) & CENTER)) {
 			score += LongDiagonalBishop
 			if tuning {
-				T.LongDiagonalBishop++
+				e.T.LongDiagonalBishop++
 			}
 		}
 		if SquareMask[fromId]&whiteOutpustRanks != 0 && outpustMask[White][fromId]&(pos.Pieces[Pawn]&pos.Colours[Black]) == 0 {
 			if PawnAttacks[Black][fromId]&(pos.Pieces[Pawn]&pos.Colours[White]) != 0 {
 				score += BishopOutpostDefendedBonus
 				if tuning {
-					T.BishopOutpostDefendedBonus++
+					e.T.BishopOutpostDefendedBonus++
 				}
 			} else {
 				score += BishopOutpostUndefendedBonus
 				if tuning {
-					T.BishopOutpostUndefendedBonus++
+					e.T.BishopOutpostUndefendedBonus++
 				}
 			}
 		}
@@ -811,7 +829,7 @@ func Evaluate(pos *Position) int {
 		}
 		score += BishopRammedPawns * rammedCount
 		if tuning {
-			T.BishopRammedPawns += int(rammedCount)
+			e.T.BishopRammedPawns += int(rammedCount)
 		}
 		if attacks&blackKingArea != 0 {
 			whiteKingAttacksCount += int16(PopCount(attacks & blackKingArea))
@@ -825,7 +843,7 @@ func Evaluate(pos *Position) int {
 	if MoreThanOne(pos.Pieces[Bishop] & pos.Colours[White]) {
 		score += BishopPair
 		if tuning {
-			T.BishopPair++
+			e.T.BishopPair++
 		}
 	}
 
@@ -840,9 +858,9 @@ func Evaluate(pos *Position) int {
 		score -= MobilityBonus[1][mobility]
 		score -= Psqt[Black][Bishop][fromId]
 		if tuning {
-			T.BishopValue--
-			T.PieceScores[Bishop][7-Rank(fromId)][FileMirror[File(fromId)]]--
-			T.MobilityBonus[1][mobility]--
+			e.T.BishopValue--
+			e.T.PieceScores[Bishop][7-Rank(fromId)][FileMirror[File(fromId)]]--
+			e.T.MobilityBonus[1][mobility]--
 		}
 
 		blackAttackedByTwo |= blackAttacked & attacks
@@ -852,25 +870,25 @@ func Evaluate(pos *Position) int {
 		if (pos.Pieces[Pawn]<<8)&SquareMask[fromId] != 0 {
 			score -= MinorBehindPawn
 			if tuning {
-				T.MinorBehindPawn--
+				e.T.MinorBehindPawn--
 			}
 		}
 		if (LONG_DIAGONALS&SquareMask[fromId]) != 0 && (MoreThanOne(BishopAttacks(fromId, pos.Pieces[Pawn]) & CENTER)) {
 			score -= LongDiagonalBishop
 			if tuning {
-				T.LongDiagonalBishop--
+				e.T.LongDiagonalBishop--
 			}
 		}
 		if SquareMask[fromId]&blackOutpustRanks != 0 && outpustMask[Black][fromId]&(pos.Pieces[Pawn]&pos.Colours[White]) == 0 {
 			if PawnAttacks[White][fromId]&(pos.Pieces[Pawn]&pos.Colours[Black]) != 0 {
 				score -= BishopOutpostDefendedBonus
 				if tuning {
-					T.BishopOutpostDefendedBonus--
+					e.T.BishopOutpostDefendedBonus--
 				}
 			} else {
 				score -= BishopOutpostUndefendedBonus
 				if tuning {
-					T.BishopOutpostUndefendedBonus--
+					e.T.BishopOutpostUndefendedBonus--
 				}
 			}
 		}
@@ -882,7 +900,7 @@ func Evaluate(pos *Position) int {
 		}
 		score -= BishopRammedPawns * rammedCount
 		if tuning {
-			T.BishopRammedPawns -= int(rammedCount)
+			e.T.BishopRammedPawns -= int(rammedCount)
 		}
 		if attacks&whiteKingArea != 0 {
 			blackKingAttacksCount += int16(PopCount(attacks & whiteKingArea))
@@ -895,7 +913,7 @@ func Evaluate(pos *Position) int {
 		score -= BishopPair
 
 		if tuning {
-			T.BishopPair--
+			e.T.BishopPair--
 		}
 	}
 
@@ -910,9 +928,9 @@ func Evaluate(pos *Position) int {
 		score += Psqt[White][Rook][fromId]
 
 		if tuning {
-			T.RookValue++
-			T.PieceScores[Rook][Rank(fromId)][FileMirror[File(fromId)]]++
-			T.MobilityBonus[2][mobility]++
+			e.T.RookValue++
+			e.T.PieceScores[Rook][Rank(fromId)][FileMirror[File(fromId)]]++
+			e.T.MobilityBonus[2][mobility]++
 		}
 
 		whiteAttackedByTwo |= whiteAttacked & attacks
@@ -922,19 +940,19 @@ func Evaluate(pos *Position) int {
 		if pos.Pieces[Pawn]&FILES[File(fromId)] == 0 {
 			score += RookOnFile[1]
 			if tuning {
-				T.RookOnFile[1]++
+				e.T.RookOnFile[1]++
 			}
 		} else if (pos.Pieces[Pawn]&pos.Colours[White])&FILES[File(fromId)] == 0 {
 			score += RookOnFile[0]
 			if tuning {
-				T.RookOnFile[0]++
+				e.T.RookOnFile[0]++
 			}
 		}
 
 		if FileBB(fromId)&pos.Pieces[Queen] != 0 {
 			score += RookOnQueenFile
 			if tuning {
-				T.RookOnQueenFile++
+				e.T.RookOnQueenFile++
 			}
 		}
 
@@ -956,9 +974,9 @@ func Evaluate(pos *Position) int {
 		score -= Psqt[Black][Rook][fromId]
 
 		if tuning {
-			T.RookValue--
-			T.PieceScores[Rook][7-Rank(fromId)][FileMirror[File(fromId)]]--
-			T.MobilityBonus[2][mobility]--
+			e.T.RookValue--
+			e.T.PieceScores[Rook][7-Rank(fromId)][FileMirror[File(fromId)]]--
+			e.T.MobilityBonus[2][mobility]--
 		}
 
 		blackAttackedByTwo |= blackAttacked & attacks
@@ -968,19 +986,19 @@ func Evaluate(pos *Position) int {
 		if pos.Pieces[Pawn]&FILES[File(fromId)] == 0 {
 			score -= RookOnFile[1]
 			if tuning {
-				T.RookOnFile[1]--
+				e.T.RookOnFile[1]--
 			}
 		} else if (pos.Pieces[Pawn]&pos.Colours[Black])&FILES[File(fromId)] == 0 {
 			score -= RookOnFile[0]
 			if tuning {
-				T.RookOnFile[0]--
+				e.T.RookOnFile[0]--
 			}
 		}
 
 		if FileBB(fromId)&pos.Pieces[Queen] != 0 {
 			score -= RookOnQueenFile
 			if tuning {
-				T.RookOnQueenFile--
+				e.T.RookOnQueenFile--
 			}
 		}
 
@@ -1002,9 +1020,9 @@ func Evaluate(pos *Position) int {
 		score += Psqt[White][Queen][fromId]
 
 		if tuning {
-			T.QueenValue++
-			T.PieceScores[Queen][Rank(fromId)][FileMirror[File(fromId)]]++
-			T.MobilityBonus[3][mobility]++
+			e.T.QueenValue++
+			e.T.PieceScores[Queen][Rank(fromId)][FileMirror[File(fromId)]]++
+			e.T.MobilityBonus[3][mobility]++
 		}
 
 		whiteAttackedByTwo |= whiteAttacked & attacks
@@ -1029,9 +1047,9 @@ func Evaluate(pos *Position) int {
 		score -= Psqt[Black][Queen][fromId]
 
 		if tuning {
-			T.QueenValue--
-			T.PieceScores[Queen][7-Rank(fromId)][FileMirror[File(fromId)]]--
-			T.MobilityBonus[3][mobility]--
+			e.T.QueenValue--
+			e.T.PieceScores[Queen][7-Rank(fromId)][FileMirror[File(fromId)]]--
+			e.T.MobilityBonus[3][mobility]--
 		}
 
 		blackAttackedByTwo |= blackAttacked & attacks
@@ -1055,8 +1073,8 @@ func Evaluate(pos *Position) int {
 	score += Psqt[White][King][whiteKingLocation]
 	score += KingDefenders[whiteKingDefenders]
 	if tuning {
-		T.PieceScores[King][Rank(whiteKingLocation)][FileMirror[File(whiteKingLocation)]]++
-		T.KingDefenders[whiteKingDefenders]++
+		e.T.PieceScores[King][Rank(whiteKingLocation)][FileMirror[File(whiteKingLocation)]]++
+		e.T.KingDefenders[whiteKingDefenders]++
 	}
 
 	// Weak squares are attacked by the enemy, defended no more
@@ -1097,8 +1115,8 @@ func Evaluate(pos *Position) int {
 	score -= Psqt[Black][King][blackKingLocation]
 	score -= KingDefenders[blackKingDefenders]
 	if tuning {
-		T.PieceScores[King][7-Rank(blackKingLocation)][FileMirror[File(blackKingLocation)]]--
-		T.KingDefenders[blackKingDefenders]--
+		e.T.PieceScores[King][7-Rank(blackKingLocation)][FileMirror[File(blackKingLocation)]]--
+		e.T.KingDefenders[blackKingDefenders]--
 	}
 
 	// Weak squares are attacked by the enemy, defended no more
@@ -1141,7 +1159,7 @@ func Evaluate(pos *Position) int {
 			threatenedPiece := pos.TypeOnSquare(SquareMask[fromId])
 			score += ThreatByMinor[threatenedPiece]
 			if tuning {
-				T.ThreatByMinor[threatenedPiece]++
+				e.T.ThreatByMinor[threatenedPiece]++
 			}
 		}
 
@@ -1150,14 +1168,14 @@ func Evaluate(pos *Position) int {
 			threatenedPiece := pos.TypeOnSquare(SquareMask[fromId])
 			score += ThreatByRook[threatenedPiece]
 			if tuning {
-				T.ThreatByRook[threatenedPiece]++
+				e.T.ThreatByRook[threatenedPiece]++
 			}
 		}
 
 		if weakForBlack&pos.Colours[Black]&whiteAttackedBy[King] != 0 {
 			score += ThreatByKing
 			if tuning {
-				T.ThreatByKing++
+				e.T.ThreatByKing++
 			}
 		}
 
@@ -1166,7 +1184,7 @@ func Evaluate(pos *Position) int {
 			Score(PopCount((pos.Colours[Black] & ^pos.Pieces[Pawn] & whiteAttackedByTwo)&weakForBlack))
 
 		if tuning {
-			T.Hanging += PopCount((pos.Colours[Black] & ^pos.Pieces[Pawn] & whiteAttackedByTwo) & weakForBlack)
+			e.T.Hanging += PopCount((pos.Colours[Black] & ^pos.Pieces[Pawn] & whiteAttackedByTwo) & weakForBlack)
 		}
 
 	}
@@ -1180,7 +1198,7 @@ func Evaluate(pos *Position) int {
 			threatenedPiece := pos.TypeOnSquare(SquareMask[fromId])
 			score -= ThreatByMinor[threatenedPiece]
 			if tuning {
-				T.ThreatByMinor[threatenedPiece]--
+				e.T.ThreatByMinor[threatenedPiece]--
 			}
 		}
 
@@ -1189,14 +1207,14 @@ func Evaluate(pos *Position) int {
 			threatenedPiece := pos.TypeOnSquare(SquareMask[fromId])
 			score -= ThreatByRook[threatenedPiece]
 			if tuning {
-				T.ThreatByRook[threatenedPiece]--
+				e.T.ThreatByRook[threatenedPiece]--
 			}
 		}
 
 		if weakForWhite&pos.Colours[White]&blackAttackedBy[King] != 0 {
 			score -= ThreatByKing
 			if tuning {
-				T.ThreatByKing--
+				e.T.ThreatByKing--
 			}
 		}
 
@@ -1205,20 +1223,29 @@ func Evaluate(pos *Position) int {
 			Score(PopCount(pos.Colours[White] & ^pos.Pieces[Pawn] & blackAttackedByTwo & weakForWhite))
 
 		if tuning {
-			T.Hanging -= PopCount(pos.Colours[White] & ^pos.Pieces[Pawn] & blackAttackedByTwo & weakForWhite)
+			e.T.Hanging -= PopCount(pos.Colours[White] & ^pos.Pieces[Pawn] & blackAttackedByTwo & weakForWhite)
 		}
 	}
 
 	// Scale Factor inlined
-	scale := SCALE_NORMAL
-	if OnlyOne(pos.Colours[Black]&pos.Pieces[Bishop]) &&
-		OnlyOne(pos.Colours[White]&pos.Pieces[Bishop]) &&
-		OnlyOne(pos.Pieces[Bishop]&WHITE_SQUARES) &&
-		(pos.Pieces[Knight]|pos.Pieces[Rook]|pos.Pieces[Queen]) == 0 {
-		scale = SCALE_HARD
-	} else if (score.End() > 0 && PopCount(pos.Colours[White]) == 2 && (pos.Colours[White]&(pos.Pieces[Bishop]|pos.Pieces[Knight])) != 0) ||
-		(score.End() < 0 && PopCount(pos.Colours[Black]) == 2 && (pos.Colours[Black]&(pos.Pieces[Bishop]|pos.Pieces[Knight])) != 0) {
+	scale := scaleUnknown
+	if hasEndgame && eg.scale != nil {
+		scale = eg.scale(pos, eg.strongSide)
+	}
+	if scale == SCALE_DRAW {
 		return SCALE_DRAW
+	} else if scale == scaleUnknown {
+		if OnlyOne(pos.Colours[Black]&pos.Pieces[Bishop]) &&
+			OnlyOne(pos.Colours[White]&pos.Pieces[Bishop]) &&
+			OnlyOne(pos.Pieces[Bishop]&WHITE_SQUARES) &&
+			(pos.Pieces[Knight]|pos.Pieces[Rook]|pos.Pieces[Queen]) == 0 {
+			scale = SCALE_HARD
+		} else if (score.End() > 0 && PopCount(pos.Colours[White]) == 2 && (pos.Colours[White]&(pos.Pieces[Bishop]|pos.Pieces[Knight])) != 0) ||
+			(score.End() < 0 && PopCount(pos.Colours[Black]) == 2 && (pos.Colours[Black]&(pos.Pieces[Bishop]|pos.Pieces[Knight])) != 0) {
+			return SCALE_DRAW
+		} else {
+			scale = SCALE_NORMAL
+		}
 	}
 
 	// tapering eval
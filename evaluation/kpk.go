@@ -0,0 +1,160 @@
+package evaluation
+
+import (
+	. "github.com/mhib/combusken/backend"
+)
+
+// King and pawn against king bitbase generated by retrograde analysis.
+// Positions are normalised so the strong side is white and the pawn is on files A-D.
+// Index consists of white king square, black king square, side to move, pawn file and pawn rank.
+
+const kpkSize = 2 * 24 * 64 * 64
+
+const (
+	kpkInvalid = 0
+	kpkUnknown = 1
+	kpkDraw    = 2
+	kpkWin     = 4
+)
+
+var kpkBitbase [kpkSize / 32]uint32
+
+func kpkIndex(sideToMove, blackKing, whiteKing, pawn int) int {
+	return whiteKing | blackKing<<6 | sideToMove<<12 | File(pawn)<<13 | (RANK_7-Rank(pawn))<<15
+}
+
+type kpkPosition struct {
+	sideToMove int
+	kings      [2]int
+	pawn       int
+	result     uint8
+}
+
+func newKPKPosition(idx int) (res kpkPosition) {
+	res.kings[White] = idx & 0x3F
+	res.kings[Black] = (idx >> 6) & 0x3F
+	res.sideToMove = (idx >> 12) & 0x1
+	res.pawn = (RANK_7-(idx>>15))*8 + (idx>>13)&0x3
+	whiteKing, blackKing, pawn := res.kings[White], res.kings[Black], res.pawn
+
+	if distance(whiteKing, blackKing) <= 1 || whiteKing == pawn || blackKing == pawn ||
+		(res.sideToMove == White && PawnAttacks[White][pawn]&SquareMask[blackKing] != 0) {
+		// Kings touch, overlap with pawn or side not to move is in check
+		res.result = kpkInvalid
+	} else if res.sideToMove == White && Rank(pawn) == RANK_7 && whiteKing != pawn+8 &&
+		(distance(blackKing, pawn+8) > 1 || KingAttacks[whiteKing]&SquareMask[pawn+8] != 0) {
+		// Pawn promotes without being captured
+		res.result = kpkWin
+	} else if res.sideToMove == Black &&
+		(KingAttacks[blackKing]&^(KingAttacks[whiteKing]|PawnAttacks[White][pawn]) == 0 ||
+			KingAttacks[blackKing]&SquareMask[pawn]&^KingAttacks[whiteKing] != 0) {
+		// Stalemate or pawn can be captured
+		res.result = kpkDraw
+	} else {
+		res.result = kpkUnknown
+	}
+	return
+}
+
+// classify sets result of position if results of all its children allow it
+func (p *kpkPosition) classify(db []kpkPosition) uint8 {
+	them := p.sideToMove ^ 1
+	good, bad := uint8(kpkWin), uint8(kpkDraw)
+	if p.sideToMove == Black {
+		good, bad = kpkDraw, kpkWin
+	}
+
+	var r uint8 = kpkInvalid
+	for b := KingAttacks[p.kings[p.sideToMove]]; b != 0; b &= b - 1 {
+		to := BitScan(b)
+		if p.sideToMove == White {
+			r |= db[kpkIndex(them, p.kings[Black], to, p.pawn)].result
+		} else {
+			r |= db[kpkIndex(them, to, p.kings[White], p.pawn)].result
+		}
+	}
+
+	if p.sideToMove == White {
+		// Single push, promotion is handled in newKPKPosition
+		if Rank(p.pawn) < RANK_7 {
+			r |= db[kpkIndex(them, p.kings[Black], p.kings[White], p.pawn+8)].result
+		}
+		// Double push
+		if Rank(p.pawn) == RANK_2 && p.pawn+8 != p.kings[White] && p.pawn+8 != p.kings[Black] {
+			r |= db[kpkIndex(them, p.kings[Black], p.kings[White], p.pawn+16)].result
+		}
+	}
+
+	if r&good != 0 {
+		p.result = good
+	} else if r&kpkUnknown != 0 {
+		p.result = kpkUnknown
+	} else {
+		p.result = bad
+	}
+	return p.result
+}
+
+func initKPK() {
+	db := make([]kpkPosition, kpkSize)
+	for idx := range db {
+		db[idx] = newKPKPosition(idx)
+	}
+	// Iterate until no unknown position can be resolved
+	for changed := true; changed; {
+		changed = false
+		for idx := range db {
+			if db[idx].result == kpkUnknown && db[idx].classify(db) != kpkUnknown {
+				changed = true
+			}
+		}
+	}
+	// Positions that are still unknown are draws
+	for idx := range db {
+		if db[idx].result == kpkWin {
+			kpkBitbase[idx/32] |= 1 << uint(idx&31)
+		}
+	}
+}
+
+func init() {
+	initKPK()
+}
+
+// probeKPK returns true if the strong side wins in king and pawn against king endgame
+func probeKPK(pos *Position, strongSide int) bool {
+	strongKing := relativeSquare(strongSide, kingSquare(pos, strongSide))
+	weakKing := relativeSquare(strongSide, kingSquare(pos, strongSide^1))
+	pawn := relativeSquare(strongSide, BitScan(pos.Pieces[Pawn]))
+	if File(pawn) > FILE_D {
+		strongKing ^= 7
+		weakKing ^= 7
+		pawn ^= 7
+	}
+	sideToMove := White
+	if pos.SideToMove != strongSide {
+		sideToMove = Black
+	}
+	idx := kpkIndex(sideToMove, weakKing, strongKing, pawn)
+	return kpkBitbase[idx/32]&(1<<uint(idx&31)) != 0
+}
+
+// IsKPKDraw returns true if position is king and pawn against king that cannot be won
+func IsKPKDraw(pos *Position) bool {
+	if PopCount(pos.Colours[White]|pos.Colours[Black]) != 3 || pos.Pieces[Pawn] == 0 {
+		return false
+	}
+	strongSide := White
+	if pos.Pieces[Pawn]&pos.Colours[Black] != 0 {
+		strongSide = Black
+	}
+	return !probeKPK(pos, strongSide)
+}
+
+func evaluateKPK(pos *Position, strongSide int) int {
+	if !probeKPK(pos, strongSide) {
+		return 0
+	}
+	pawn := relativeSquare(strongSide, BitScan(pos.Pieces[Pawn]))
+	return KnownWin + int(PawnValue.End()) + 10*Rank(pawn)
+}
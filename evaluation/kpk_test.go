@@ -0,0 +1,60 @@
+package evaluation
+
+import (
+	"testing"
+
+	. "github.com/mhib/combusken/backend"
+)
+
+var kpkTests = []struct {
+	fen  string
+	draw bool
+}{
+	// Rook pawn with defending king in the corner
+	{"k7/8/8/8/P7/8/8/K7 w - - 0 1", true},
+	// King on sixth rank in front of the pawn
+	{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", false},
+	{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", false},
+	// Win with the move, stalemate without it
+	{"4k3/4P3/4K3/8/8/8/8/8 w - - 0 1", false},
+	{"4k3/4P3/4K3/8/8/8/8/8 b - - 0 1", true},
+	{"8/8/8/8/8/3k4/3p4/3K4 b - - 0 1", false},
+	{"8/8/8/8/8/3k4/3p4/3K4 w - - 0 1", true},
+	// Opposition
+	{"8/8/3k4/8/3K4/3P4/8/8 w - - 0 1", true},
+	{"8/8/3k4/8/3K4/3P4/8/8 b - - 0 1", false},
+	// Rule of the square
+	{"8/8/8/2k5/P7/8/8/7K w - - 0 1", true},
+	{"7k/8/8/P7/8/8/8/7K w - - 0 1", false},
+	// Mirrored files
+	{"7k/8/8/8/7P/8/8/7K w - - 0 1", true},
+}
+
+func TestKPK(t *testing.T) {
+	table := NewPawnKingTable(1)
+	evaluator := Evaluator{PawnKingTable: &table}
+	for _, test := range kpkTests {
+		pos := ParseFen(test.fen)
+		if IsKPKDraw(&pos) != test.draw {
+			t.Errorf("%s: expected draw %v", test.fen, test.draw)
+		}
+		val := evaluator.Evaluate(&pos)
+		if test.draw && val != 0 {
+			t.Errorf("%s: expected draw evaluation, got %d", test.fen, val)
+		}
+		if !test.draw && (val >= KnownWin) != (pos.Pieces[Pawn]&pos.Colours[pos.SideToMove] != 0) {
+			t.Errorf("%s: expected known win evaluation, got %d", test.fen, val)
+		}
+	}
+}
+
+func TestKPKWins(t *testing.T) {
+	wins := 0
+	for _, entry := range kpkBitbase {
+		wins += PopCount(uint64(entry))
+	}
+	// Number of won positions in bitbase normalised to files A-D
+	if wins != 111282 {
+		t.Errorf("Unexpected number of won positions %d", wins)
+	}
+}
@@ -0,0 +1,25 @@
+package evaluation
+
+import (
+	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/nnue"
+	. "github.com/mhib/combusken/utils"
+)
+
+// evaluateNetwork returns neural network evaluation from side to move perspective.
+// Scores are kept below scores of known wins.
+func evaluateNetwork(pos *Position, net *nnue.Network, eg endgame, hasEndgame bool) int {
+	if hasEndgame && eg.scale != nil && eg.scale(pos, eg.strongSide) == SCALE_DRAW {
+		return 0
+	}
+	if pos.Accumulator == nil {
+		// Position outside of search is evaluated with temporary accumulator
+		withAccumulator := *pos
+		withAccumulator.Accumulator = new(nnue.Accumulator)
+		pos = &withAccumulator
+	}
+	if !pos.Accumulator.IsComputed(net) {
+		pos.RefreshAccumulator(net)
+	}
+	return Max(-KnownWin+1, Min(KnownWin-1, net.Evaluate(pos.Accumulator, pos.SideToMove)))
+}
@@ -0,0 +1,35 @@
+package evaluation
+
+import (
+	"testing"
+
+	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/nnue"
+)
+
+func TestNetworkEvaluation(t *testing.T) {
+	table := NewPawnKingTable(1)
+	evaluator := Evaluator{PawnKingTable: &table}
+	// Network evaluates every position as 1.0
+	net := nnue.NewNetwork(1, 1)
+	net.FeatureBiases[0] = nnue.ActivationScale
+	net.HiddenWeights[0] = nnue.WeightScale
+	net.OutputWeights[0] = nnue.WeightScale
+	evaluator.Network = net
+
+	for _, test := range []struct {
+		fen      string
+		expected int
+	}{
+		{InitialPositionFen, nnue.EvalScale},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", nnue.EvalScale},
+		// Known endgames are not evaluated by network
+		{"8/8/3k4/8/8/3NN3/3K4/8 w - - 0 1", 0},
+		{"8/8/8/8/8/1k6/p7/K7 b - - 0 1", 0},
+	} {
+		pos := ParseFen(test.fen)
+		if res := evaluator.Evaluate(&pos); res != test.expected {
+			t.Errorf("%s: expected %d, got %d", test.fen, test.expected, res)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package evaluation
+
+import (
+	"math"
+
+	. "github.com/mhib/combusken/backend"
+)
+
+// Win, draw and loss probabilities are modelled with two logistic functions of score,
+// win probability is 1 / (1 + exp((a - score) / b)) and loss probability is the same for negated score.
+// Parameters a and b are cubic polynomials of material left on the board, fitted by `combusken fit-wdl`.
+// DefaultWDLModel was fitted to 174629 positions from 1752 self-play games at 10000 nodes per move.
+
+type WDLModel struct {
+	A [4]float64
+	B [4]float64
+}
+
+var DefaultWDLModel = WDLModel{
+	A: [4]float64{564.1751843351439, -1894.1197266129016, 2574.8156442829923, -1140.823635551595},
+	B: [4]float64{142.35888172924555, 14.700318588811632, -186.64392613462496, 238.95410730756123},
+}
+
+var materialWeights = [King]int{1, 3, 3, 5, 9}
+
+// startMaterial is material of both sides in the initial position
+const startMaterial = 2 * (8*1 + 2*3 + 2*3 + 2*5 + 9)
+
+// WDLMaterial returns material of both sides in range from 0 for bare kings to 1 for the initial position
+func WDLMaterial(pos *Position) float64 {
+	material := 0
+	for kind := Pawn; kind < King; kind++ {
+		material += materialWeights[kind] * PopCount(pos.Pieces[kind])
+	}
+	return math.Min(float64(material)/startMaterial, 1)
+}
+
+// Parameters returns a and b of logistic functions for given material
+func (m *WDLModel) Parameters(material float64) (a, b float64) {
+	for i := len(m.A) - 1; i >= 0; i-- {
+		a = a*material + m.A[i]
+		b = b*material + m.B[i]
+	}
+	return
+}
+
+// Probabilities returns probabilities of win, draw and loss for score from side to move perspective
+func (m *WDLModel) Probabilities(score int, material float64) (win, draw, loss float64) {
+	a, b := m.Parameters(material)
+	win = 1 / (1 + math.Exp((a-float64(score))/b))
+	loss = 1 / (1 + math.Exp((a+float64(score))/b))
+	draw = math.Max(1-win-loss, 0)
+	return
+}
+
+// WDL returns win, draw and loss probabilities in permille, they always sum to 1000
+func (m *WDLModel) WDL(score int, material float64) (win, draw, loss int) {
+	w, _, l := m.Probabilities(score, material)
+	win = int(math.Round(1000 * w))
+	loss = int(math.Round(1000 * l))
+	if win+loss > 1000 {
+		loss = 1000 - win
+	}
+	return win, 1000 - win - loss, loss
+}
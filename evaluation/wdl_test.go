@@ -0,0 +1,61 @@
+package evaluation
+
+import (
+	"testing"
+
+	. "github.com/mhib/combusken/backend"
+	. "github.com/mhib/combusken/utils"
+)
+
+func TestWDL(t *testing.T) {
+	if material := WDLMaterial(&InitialPosition); material != 1 {
+		t.Errorf("Expected material of initial position to be 1, got %f", material)
+	}
+	pos := ParseFen("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if material := WDLMaterial(&pos); material != 0 {
+		t.Errorf("Expected material of bare kings to be 0, got %f", material)
+	}
+
+	for _, material := range []float64{0, 0.5, 1} {
+		lastWin := -1
+		for score := -1000; score <= 1000; score += 50 {
+			win, draw, loss := DefaultWDLModel.WDL(score, material)
+			if win+draw+loss != 1000 || win < 0 || draw < 0 || loss < 0 {
+				t.Errorf("Invalid WDL %d %d %d for score %d", win, draw, loss, score)
+			}
+			if win < lastWin {
+				t.Errorf("Win rate decreases with score %d", score)
+			}
+			lastWin = win
+			mirroredWin, _, mirroredLoss := DefaultWDLModel.WDL(-score, material)
+			if win != mirroredLoss || loss != mirroredWin {
+				t.Errorf("WDL is not symmetric for score %d", score)
+			}
+		}
+		if win, _, _ := DefaultWDLModel.WDL(Mate-10, material); win != 1000 {
+			t.Errorf("Expected certain win for mate score, got %d", win)
+		}
+	}
+}
+
+func TestWDLRates(t *testing.T) {
+	for _, material := range []float64{0, 0.5, 1} {
+		if win, _, _ := DefaultWDLModel.WDL(0, material); win >= 500 {
+			t.Errorf("Expected equal position not to be won, got win rate %d for material %f", win, material)
+		}
+	}
+	if _, draw, _ := DefaultWDLModel.WDL(100, 0); draw < 900 {
+		t.Errorf("Expected small advantage with bare kings to be a draw, got draw rate %d", draw)
+	}
+	if win, _, loss := DefaultWDLModel.WDL(300, 0.5); win < 600 || loss > 100 {
+		t.Errorf("Expected piece up in middlegame to be mostly won, got win rate %d and loss rate %d", win, loss)
+	}
+	if endgame, _, _ := DefaultWDLModel.WDL(300, 0.25); endgame >= 800 {
+		t.Errorf("Expected piece up with little material left to be often drawn, got win rate %d", endgame)
+	}
+	for _, material := range []float64{0.25, 0.5, 1} {
+		if win, _, _ := DefaultWDLModel.WDL(500, material); win < 800 {
+			t.Errorf("Expected rook up to be won, got win rate %d for material %f", win, material)
+		}
+	}
+}
@@ -9,3 +9,7 @@ const (
 )
 
 const TB_RESULT_FAILED = int64(0xFFFFFFFF)
+
+// Score of won moves ranked by ProbeRoot, lost moves have negated score.
+// Cursed wins and blessed losses are scored below a pawn.
+const TB_WIN_SCORE = 32000 - 255 - 1
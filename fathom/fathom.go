@@ -11,8 +11,9 @@ import "github.com/mhib/combusken/backend"
 import "strings"
 
 var MAX_PIECE_COUNT = 0
-var MIN_PROBE_DEPTH = 0
 
+// SetPath loads Syzygy tables from path list.
+// Fathom keeps tables in global state, so they are shared by the whole process.
 func SetPath(path string) {
 	cPath := C.CString(strings.TrimSpace(path))
 	defer C.free(unsafe.Pointer(cPath))
@@ -41,17 +42,17 @@ func ProbeWDL(pos *backend.Position, depth int) int64 {
 	))
 }
 
-func IsWDLProbeable(pos *backend.Position, depth int) bool {
+func IsWDLProbeable(pos *backend.Position, depth, minProbeDepth int) bool {
 	return MAX_PIECE_COUNT != 0 &&
 		pos.FiftyMove == 0 &&
 		pos.EpSquare == 0 &&
 		pos.Flags == 0xF &&
-		depthCardinalityCheck(pos, depth)
+		depthCardinalityCheck(pos, depth, minProbeDepth)
 }
 
-func depthCardinalityCheck(pos *backend.Position, depth int) bool {
+func depthCardinalityCheck(pos *backend.Position, depth, minProbeDepth int) bool {
 	cardinality := backend.PopCount(pos.Colours[backend.White] | pos.Colours[backend.Black])
-	return cardinality < MAX_PIECE_COUNT || (cardinality == MAX_PIECE_COUNT && depth >= MIN_PROBE_DEPTH)
+	return cardinality < MAX_PIECE_COUNT || (cardinality == MAX_PIECE_COUNT && depth >= minProbeDepth)
 }
 
 func IsDTZProbeable(pos *backend.Position) bool {
@@ -60,16 +61,18 @@ func IsDTZProbeable(pos *backend.Position) bool {
 
 var promoteTranslation = [...]int{backend.None, backend.Queen, backend.Rook, backend.Bishop, backend.Knight}
 
-func ProbeDTZ(pos *backend.Position, moves []backend.EvaledMove) (bool, backend.Move, int, int) {
-	var epSquare int
-
+// epSquare converts square of pawn that moved two squares to square behind it
+func epSquare(pos *backend.Position) int {
 	if pos.EpSquare == 0 {
-		epSquare = 0
+		return 0
 	} else if pos.SideToMove == backend.White {
-		epSquare = pos.EpSquare + 8
-	} else {
-		epSquare = pos.EpSquare - 8
+		return pos.EpSquare + 8
 	}
+	return pos.EpSquare - 8
+}
+
+func ProbeDTZ(pos *backend.Position, moves []backend.EvaledMove) (bool, backend.Move, int, int) {
+	epSquare := epSquare(pos)
 	result := uint(C.tb_probe_root(
 		C.uint64_t(pos.Colours[backend.White]),
 		C.uint64_t(pos.Colours[backend.Black]),
@@ -95,16 +98,81 @@ func ProbeDTZ(pos *backend.Position, moves []backend.EvaledMove) (bool, backend.
 	to := int(C.tb_get_to_go(C.uint(result)))
 	promotion := promoteTranslation[uint(C.tb_get_promotes_go(C.uint(result)))]
 
-	for _, move := range moves {
+	if idx := findMove(moves, from, to, promotion); idx >= 0 {
+		return true, moves[idx].Move, wdl, dtz
+	}
+	return false, backend.NullMove, 0, 0
+}
+
+func findMove(moves []backend.EvaledMove, from, to, promotion int) int {
+	for i, move := range moves {
 		if move.From() == from && move.To() == to {
 			if promotion != backend.None {
 				if move.IsPromotion() && move.PromotedPiece() == promotion {
-					return true, move.Move, wdl, dtz
+					return i
 				}
 			} else {
-				return true, move.Move, wdl, dtz
+				return i
 			}
 		}
 	}
-	return false, backend.NullMove, 0, 0
+	return -1
+}
+
+// ProbeRoot ranks root moves with DTZ tables, or with WDL tables if DTZ tables are missing.
+// Moves with higher rank are better, scores are from side to move perspective.
+// Second result is true if DTZ tables were used.
+func ProbeRoot(pos *backend.Position, moves []backend.EvaledMove, hasRepeated, useRule50 bool, ranks, scores []int) (bool, bool) {
+	var results C.struct_TbRootMoves
+	dtz := true
+	ok := C.tb_probe_root_dtz(
+		C.uint64_t(pos.Colours[backend.White]),
+		C.uint64_t(pos.Colours[backend.Black]),
+		C.uint64_t(pos.Pieces[backend.King]),
+		C.uint64_t(pos.Pieces[backend.Queen]),
+		C.uint64_t(pos.Pieces[backend.Rook]),
+		C.uint64_t(pos.Pieces[backend.Bishop]),
+		C.uint64_t(pos.Pieces[backend.Knight]),
+		C.uint64_t(pos.Pieces[backend.Pawn]),
+		C.uint(pos.FiftyMove),
+		C.uint(0),
+		C.uint(epSquare(pos)),
+		C.bool(pos.SideToMove == backend.White),
+		C.bool(hasRepeated),
+		C.bool(useRule50),
+		&results,
+	)
+	if ok == 0 {
+		dtz = false
+		ok = C.tb_probe_root_wdl(
+			C.uint64_t(pos.Colours[backend.White]),
+			C.uint64_t(pos.Colours[backend.Black]),
+			C.uint64_t(pos.Pieces[backend.King]),
+			C.uint64_t(pos.Pieces[backend.Queen]),
+			C.uint64_t(pos.Pieces[backend.Rook]),
+			C.uint64_t(pos.Pieces[backend.Bishop]),
+			C.uint64_t(pos.Pieces[backend.Knight]),
+			C.uint64_t(pos.Pieces[backend.Pawn]),
+			C.uint(pos.FiftyMove),
+			C.uint(0),
+			C.uint(epSquare(pos)),
+			C.bool(pos.SideToMove == backend.White),
+			C.bool(useRule50),
+			&results,
+		)
+	}
+	if ok == 0 || int(results.size) != len(moves) {
+		return false, false
+	}
+	for i := 0; i < int(results.size); i++ {
+		rootMove := &results.moves[i]
+		move := uint(rootMove.move)
+		idx := findMove(moves, int((move>>6)&0x3F), int(move&0x3F), promoteTranslation[(move>>12)&0x7])
+		if idx < 0 {
+			return false, false
+		}
+		ranks[idx] = int(rootMove.tbRank)
+		scores[idx] = int(rootMove.tbScore)
+	}
+	return true, dtz
 }
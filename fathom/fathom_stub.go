@@ -5,8 +5,9 @@ package fathom
 import "github.com/mhib/combusken/backend"
 
 var MAX_PIECE_COUNT = 0
-var MIN_PROBE_DEPTH = 0
 
+// SetPath loads Syzygy tables from path list.
+// Fathom keeps tables in global state, so they are shared by the whole process.
 func SetPath(path string) {
 }
 
@@ -17,7 +18,7 @@ func ProbeWDL(pos *backend.Position, depth int) int64 {
 	return 0
 }
 
-func IsWDLProbeable(pos *backend.Position, depth int) bool {
+func IsWDLProbeable(pos *backend.Position, depth, minProbeDepth int) bool {
 	return false
 }
 
@@ -28,3 +29,7 @@ func IsDTZProbeable(pos *backend.Position) bool {
 func ProbeDTZ(pos *backend.Position, moves []backend.EvaledMove) (bool, backend.Move, int, int) {
 	return false, backend.NullMove, 0, 0
 }
+
+func ProbeRoot(pos *backend.Position, moves []backend.EvaledMove, hasRepeated, useRule50 bool, ranks, scores []int) (bool, bool) {
+	return false, false
+}
@@ -0,0 +1,54 @@
+package nnue
+
+// Accumulator holds outputs of input layer for both perspectives
+type Accumulator struct {
+	Values [2][MaxHalfDimensions]int16
+	// Network values were computed with
+	network *Network
+}
+
+// IsComputed returns true if accumulator holds values of network
+func (acc *Accumulator) IsComputed(n *Network) bool {
+	return acc.network == n
+}
+
+// Network returns network values were computed with, nil if they are not computed
+func (acc *Accumulator) Network() *Network {
+	return acc.network
+}
+
+// Invalidate marks values as not computed
+func (acc *Accumulator) Invalidate() {
+	acc.network = nil
+}
+
+// Reset sets values of perspective to biases
+func (acc *Accumulator) Reset(n *Network, perspective int) {
+	copy(acc.Values[perspective][:n.HalfDimensions], n.FeatureBiases)
+	acc.network = n
+}
+
+func (acc *Accumulator) Add(n *Network, perspective, feature int) {
+	values := acc.Values[perspective][:n.HalfDimensions]
+	for i, weight := range n.FeatureWeights[feature*n.HalfDimensions : (feature+1)*n.HalfDimensions] {
+		values[i] += weight
+	}
+}
+
+// Apply sets values of perspective to values of parent with features added and removed
+func (acc *Accumulator) Apply(parent *Accumulator, n *Network, perspective int, added, removed []int) {
+	half := n.HalfDimensions
+	values := acc.Values[perspective][:half]
+	copy(values, parent.Values[perspective][:half])
+	for _, feature := range added {
+		for i, weight := range n.FeatureWeights[feature*half : (feature+1)*half] {
+			values[i] += weight
+		}
+	}
+	for _, feature := range removed {
+		for i, weight := range n.FeatureWeights[feature*half : (feature+1)*half] {
+			values[i] -= weight
+		}
+	}
+	acc.network = n
+}
@@ -0,0 +1,179 @@
+// Package nnue implements efficiently updatable neural network evaluation.
+//
+// Network has HalfKP input layer: every non-king piece is a feature relative to the king
+// of the side whose perspective is computed. Outputs of input layer for both perspectives
+// are kept in accumulators, that are updated incrementally when pieces move.
+// Sides, piece kinds and squares use numbering of backend package.
+package nnue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+const (
+	black = 0
+	white = 1
+	king  = 5
+)
+
+// Number of features of a single perspective: king square, piece of either side and its square
+const FeatureCount = 64 * 2 * king * 64
+
+// MaxHalfDimensions is the largest supported size of accumulator of a single perspective
+const MaxHalfDimensions = 256
+
+const (
+	// Activations are clipped to [0, ActivationScale]
+	ActivationScale = 127
+	// Weights of hidden and output layers are multiplied by WeightScale
+	WeightScale = 64
+	// Network output of 1.0 corresponds to EvalScale centipawns
+	EvalScale = 400
+)
+
+const networkMagic = "CBNN"
+const networkVersion = 1
+
+// Network holds quantized weights.
+// Feature weights are stored feature by feature, hidden weights neuron by neuron,
+// where inputs of hidden layer are side to move accumulator followed by the other one.
+type Network struct {
+	HalfDimensions   int
+	HiddenDimensions int
+	FeatureBiases    []int16
+	FeatureWeights   []int16
+	HiddenBiases     []int32
+	HiddenWeights    []int16
+	OutputBias       int32
+	OutputWeights    []int16
+}
+
+func NewNetwork(halfDimensions, hiddenDimensions int) *Network {
+	return &Network{
+		HalfDimensions:   halfDimensions,
+		HiddenDimensions: hiddenDimensions,
+		FeatureBiases:    make([]int16, halfDimensions),
+		FeatureWeights:   make([]int16, FeatureCount*halfDimensions),
+		HiddenBiases:     make([]int32, hiddenDimensions),
+		HiddenWeights:    make([]int16, hiddenDimensions*2*halfDimensions),
+		OutputWeights:    make([]int16, hiddenDimensions),
+	}
+}
+
+// FeatureIndex returns index of piece of side on square from perspective with king on kingSquare.
+// Board is flipped vertically for black perspective.
+func FeatureIndex(perspective, kingSquare, side, kind, square int) int {
+	relativeSide := 0
+	if side != perspective {
+		relativeSide = 1
+	}
+	if perspective == black {
+		kingSquare ^= 56
+		square ^= 56
+	}
+	return ((kingSquare*king+kind)*2+relativeSide)*64 + square
+}
+
+func Load(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(bufio.NewReader(file))
+}
+
+func Read(r io.Reader) (*Network, error) {
+	header := make([]byte, len(networkMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(networkMagic)]) != networkMagic || header[len(networkMagic)] != networkVersion {
+		return nil, errors.New("Invalid network file")
+	}
+	var dimensions [2]uint16
+	if err := binary.Read(r, binary.LittleEndian, &dimensions); err != nil {
+		return nil, err
+	}
+	if dimensions[0] == 0 || dimensions[0] > MaxHalfDimensions || dimensions[1] == 0 {
+		return nil, errors.New("Invalid network dimensions")
+	}
+	n := NewNetwork(int(dimensions[0]), int(dimensions[1]))
+	for _, data := range n.parameters() {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (n *Network) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	if err = n.Write(writer); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func (n *Network) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, networkMagic); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte{networkVersion}); err != nil {
+		return err
+	}
+	dimensions := [2]uint16{uint16(n.HalfDimensions), uint16(n.HiddenDimensions)}
+	if err := binary.Write(w, binary.LittleEndian, dimensions); err != nil {
+		return err
+	}
+	for _, data := range n.parameters() {
+		if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parameters returns parameters in file order
+func (n *Network) parameters() []interface{} {
+	return []interface{}{n.FeatureBiases, n.FeatureWeights, n.HiddenBiases, n.HiddenWeights, &n.OutputBias, n.OutputWeights}
+}
+
+// Evaluate returns score in centipawns from side to move perspective
+func (n *Network) Evaluate(acc *Accumulator, sideToMove int) int {
+	var input [2 * MaxHalfDimensions]int32
+	half := n.HalfDimensions
+	for i, value := range acc.Values[sideToMove][:half] {
+		input[i] = clippedReLU(int32(value))
+	}
+	for i, value := range acc.Values[sideToMove^1][:half] {
+		input[half+i] = clippedReLU(int32(value))
+	}
+	output := n.OutputBias
+	for neuron := 0; neuron < n.HiddenDimensions; neuron++ {
+		sum := n.HiddenBiases[neuron]
+		for i, weight := range n.HiddenWeights[neuron*2*half : (neuron+1)*2*half] {
+			sum += int32(weight) * input[i]
+		}
+		output += int32(n.OutputWeights[neuron]) * clippedReLU(sum/WeightScale)
+	}
+	return int(int64(output) * EvalScale / (ActivationScale * WeightScale))
+}
+
+func clippedReLU(value int32) int32 {
+	if value < 0 {
+		return 0
+	} else if value > ActivationScale {
+		return ActivationScale
+	}
+	return value
+}
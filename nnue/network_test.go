@@ -0,0 +1,70 @@
+package nnue
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func randomNetwork(rng *rand.Rand, halfDimensions, hiddenDimensions int) *Network {
+	n := NewNetwork(halfDimensions, hiddenDimensions)
+	for i := range n.FeatureBiases {
+		n.FeatureBiases[i] = int16(rng.Intn(64))
+	}
+	for i := range n.FeatureWeights {
+		n.FeatureWeights[i] = int16(rng.Intn(33) - 16)
+	}
+	for i := range n.HiddenBiases {
+		n.HiddenBiases[i] = int32(rng.Intn(2001) - 1000)
+	}
+	for i := range n.HiddenWeights {
+		n.HiddenWeights[i] = int16(rng.Intn(129) - 64)
+	}
+	n.OutputBias = int32(rng.Intn(2001) - 1000)
+	for i := range n.OutputWeights {
+		n.OutputWeights[i] = int16(rng.Intn(129) - 64)
+	}
+	return n
+}
+
+func TestReadWrite(t *testing.T) {
+	n := randomNetwork(rand.New(rand.NewSource(1)), 16, 8)
+	var buffer bytes.Buffer
+	if err := n.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Read(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n, res) {
+		t.Error("Network read differs from written one")
+	}
+	if _, err = Read(bytes.NewReader([]byte("CBTB\x01"))); err == nil {
+		t.Error("Expected error for invalid header")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	n := NewNetwork(1, 1)
+	n.HiddenWeights[0] = 2 * WeightScale
+	n.HiddenWeights[1] = -WeightScale
+	n.OutputWeights[0] = WeightScale
+	var acc Accumulator
+	acc.Values[white][0] = 50
+	acc.Values[black][0] = 30
+	// Hidden neuron is 2 * 50 - 30 for white and 2 * 30 - 50 for black
+	if res := n.Evaluate(&acc, white); res != 70*EvalScale/ActivationScale {
+		t.Errorf("Expected %d, got %d", 70*EvalScale/ActivationScale, res)
+	}
+	if res := n.Evaluate(&acc, black); res != 10*EvalScale/ActivationScale {
+		t.Errorf("Expected %d, got %d", 10*EvalScale/ActivationScale, res)
+	}
+	// Activations are clipped
+	acc.Values[white][0] = 1000
+	acc.Values[black][0] = -1000
+	if res := n.Evaluate(&acc, white); res != EvalScale {
+		t.Errorf("Expected %d, got %d", EvalScale, res)
+	}
+}
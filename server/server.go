@@ -0,0 +1,429 @@
+// Package server exposes engine through JSON HTTP API and streams progress of search over WebSocket
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/engine"
+)
+
+// Events are dropped for clients that do not keep up with that many events
+const clientBuffer = 256
+
+// Server handles requests:
+//
+//	GET  /options   options of engine
+//	GET  /position  FEN of current position
+//	POST /position  {"fen": "...", "moves": ["e2e4"]} sets position, starting one if fen is empty
+//	POST /go        engine.LimitsType as JSON, for example {"depth": 20} or {"infinite": true}, starts search
+//	POST /stop      stops search and returns its result
+//	GET  /result    state of search and result of the last one
+//	GET  /ws        WebSocket with "info" events during search and "bestmove" event after it
+//
+// Bodies of POST requests have to be sent with application/json content type.
+type Server struct {
+	engine    *engine.Engine
+	mux       *http.ServeMux
+	mu        sync.Mutex
+	positions []backend.Position
+	cancel    context.CancelFunc
+	// Closed when current search finishes, nil if there was no search
+	done    chan struct{}
+	result  *engine.SearchResult
+	clients map[chan []byte]struct{}
+}
+
+// New returns server that searches with e, NewGame has to be already called on e
+func New(e *engine.Engine) *Server {
+	s := &Server{
+		engine:    e,
+		mux:       http.NewServeMux(),
+		positions: []backend.Position{backend.InitialPosition},
+		clients:   make(map[chan []byte]struct{}),
+	}
+	// Update is called synchronously by search, so info events are sent before bestmove event
+	e.Update = func(info engine.SearchInfo) {
+		s.broadcast(event{Type: "info", searchInfo: newSearchInfo(info)})
+	}
+	s.mux.HandleFunc("/options", s.handleOptions)
+	s.mux.HandleFunc("/position", s.handlePosition)
+	s.mux.HandleFunc("/go", s.handleGo)
+	s.mux.HandleFunc("/stop", s.handleStop)
+	s.mux.HandleFunc("/result", s.handleResult)
+	s.mux.HandleFunc("/ws", s.handleWebsocket)
+	return s
+}
+
+// ServeHTTP rejects requests from web pages that are not served locally
+// and POST requests without JSON content type, so other sites cannot control engine through browser
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !localOrigin(r) {
+		writeError(w, http.StatusForbidden, errors.New("origin not allowed"))
+		return
+	}
+	if r.Method == http.MethodPost {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("content type has to be application/json"))
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// localOrigin returns true if request is not sent by browser or is sent by page served from loopback address
+func localOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if host := u.Hostname(); host == "localhost" {
+		return true
+	} else if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+	return false
+}
+
+// Stop stops search and waits until it finishes
+func (s *Server) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+	if done != nil {
+		cancel()
+		<-done
+	}
+}
+
+type searchInfo struct {
+	Depth    int             `json:"depth"`
+	SelDepth int             `json:"seldepth"`
+	Score    engine.UciScore `json:"score"`
+	// "lower" or "upper" if score is only a bound
+	Bound string         `json:"bound,omitempty"`
+	Nodes int            `json:"nodes"`
+	Nps   int            `json:"nps"`
+	Time  int            `json:"time"`
+	PV    []backend.Move `json:"pv"`
+	// Win, draw and loss rates in permille, present when UCI_ShowWDL is set
+	WDL []int `json:"wdl,omitempty"`
+}
+
+type event struct {
+	Type string `json:"type"`
+	searchInfo
+	BestMove   backend.Move `json:"bestmove,omitempty"`
+	PonderMove backend.Move `json:"ponder,omitempty"`
+}
+
+func boundName(bound engine.Bound) string {
+	switch bound {
+	case engine.BoundLower:
+		return "lower"
+	case engine.BoundUpper:
+		return "upper"
+	}
+	return ""
+}
+
+func newSearchInfo(info engine.SearchInfo) searchInfo {
+	res := searchInfo{
+		Depth:    info.Depth,
+		SelDepth: info.SelDepth,
+		Score:    info.Score,
+		Bound:    boundName(info.Bound),
+		Nodes:    info.Nodes,
+		Nps:      info.Nps,
+		Time:     info.Duration,
+		PV:       info.Moves,
+	}
+	if info.WDL != (engine.WDLScore{}) {
+		res.WDL = []int{info.WDL.Win, info.WDL.Draw, info.WDL.Loss}
+	}
+	return res
+}
+
+func newResultEvent(res *engine.SearchResult) event {
+	time := int(res.Time.Milliseconds())
+	nps := 0
+	if time > 0 {
+		nps = res.Nodes * 1000 / time
+	}
+	return event{
+		Type: "bestmove",
+		searchInfo: newSearchInfo(engine.SearchInfo{
+			Score:    res.Score,
+			Bound:    res.Bound,
+			Depth:    res.Depth,
+			SelDepth: res.SelDepth,
+			Nodes:    res.Nodes,
+			Nps:      nps,
+			Duration: time,
+			Moves:    res.PV,
+			WDL:      res.WDL,
+		}),
+		BestMove:   res.BestMove,
+		PonderMove: res.PonderMove,
+	}
+}
+
+func (s *Server) broadcast(e event) {
+	message, _ := json.Marshal(e)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		select {
+		case client <- message:
+		default:
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	return false
+}
+
+var errSearching = errors.New("search in progress")
+
+// searching has to be called with mu held
+func (s *Server) searching() bool {
+	if s.done == nil {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+type option struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+	Min   *int        `json:"min,omitempty"`
+	Max   *int        `json:"max,omitempty"`
+}
+
+func (s *Server) handleOptions(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	var res []option
+	for _, engineOption := range s.engine.GetOptions() {
+		switch o := engineOption.(type) {
+		case *engine.IntOption:
+			min, max := o.Min, o.Max
+			res = append(res, option{Name: o.Name, Type: "spin", Value: o.Val, Min: &min, Max: &max})
+		case *engine.StringOption:
+			res = append(res, option{Name: o.Name, Type: "string", Value: o.Val})
+		case *engine.CheckOption:
+			res = append(res, option{Name: o.Name, Type: "check", Value: o.Val})
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+type positionRequest struct {
+	Fen   string   `json:"fen"`
+	Moves []string `json:"moves"`
+}
+
+func (s *Server) handlePosition(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodPost {
+		var req positionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if s.searching() {
+			writeError(w, http.StatusConflict, errSearching)
+			return
+		}
+		if req.Fen == "" {
+			req.Fen = backend.InitialPositionFen
+		}
+		if err := backend.ValidateFen(req.Fen); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		positions := []backend.Position{backend.ParseFen(req.Fen)}
+		for _, move := range req.Moves {
+			next, ok := positions[len(positions)-1].MakeMoveLAN(move)
+			if !ok {
+				writeError(w, http.StatusBadRequest, errors.New("illegal move "+move))
+				return
+			}
+			positions = append(positions, next)
+		}
+		s.positions = positions
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"fen": s.positions[len(s.positions)-1].Fen()})
+}
+
+func (s *Server) handleGo(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	var limits engine.LimitsType
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.searching() {
+		writeError(w, http.StatusConflict, errSearching)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.cancel, s.done = cancel, done
+	params := engine.SearchParams{Positions: s.positions, Limits: limits}
+	go func() {
+		defer close(done)
+		res := s.engine.Analyse(ctx, params)
+		cancel()
+		s.mu.Lock()
+		s.result = &res
+		s.mu.Unlock()
+		s.broadcast(newResultEvent(&res))
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]bool{"searching": true})
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	s.Stop()
+	s.writeResult(w)
+}
+
+type resultResponse struct {
+	Searching bool   `json:"searching"`
+	Result    *event `json:"result,omitempty"`
+}
+
+func (s *Server) handleResult(w http.ResponseWriter, r *http.Request) {
+	if allowMethods(w, r, http.MethodGet) {
+		s.writeResult(w)
+	}
+}
+
+func (s *Server) writeResult(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := resultResponse{Searching: s.searching()}
+	if s.result != nil {
+		e := newResultEvent(s.result)
+		res.Result = &e
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleWebsocket(w http.ResponseWriter, r *http.Request) {
+	// Client is registered before handshake is completed, so that it receives events of searches started after it
+	messages := make(chan []byte, clientBuffer)
+	s.mu.Lock()
+	s.clients[messages] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, messages)
+		s.mu.Unlock()
+	}()
+
+	conn, rw, err := upgradeWebsocket(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Control frames read from client are answered by writer, as frames cannot be written concurrently
+	type frame struct {
+		opcode  byte
+		payload []byte
+	}
+	controls := make(chan frame, 1)
+	// Closed when writer returns, so that reader does not block on sending control frames
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(controls)
+		for {
+			opcode, payload, err := readFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			var control frame
+			switch opcode {
+			case opPing:
+				control = frame{opPong, payload}
+			case opClose:
+				control = frame{opClose, nil}
+			default:
+				continue
+			}
+			select {
+			case controls <- control:
+			case <-done:
+				return
+			}
+			if opcode == opClose {
+				return
+			}
+		}
+	}()
+
+	writer := rw.Writer
+	for {
+		select {
+		case message := <-messages:
+			if writeFrame(writer, opText, message) != nil {
+				return
+			}
+		case control, ok := <-controls:
+			if !ok {
+				return
+			}
+			if writeFrame(writer, control.opcode, control.payload) != nil || control.opcode == opClose {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mhib/combusken/engine"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	e := engine.New()
+	e.Hash.Set(4)
+	e.NewGame()
+	s := New(e)
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		s.Stop()
+		ts.Close()
+		e.Close()
+	})
+	return s, ts
+}
+
+func request(t *testing.T, method, url, body string, result interface{}) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWebsocket(t *testing.T, ts *httptest.Server) *testClient {
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: "+key+"\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Example from RFC 6455
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected handshake response %v", resp)
+	}
+	return &testClient{conn, reader}
+}
+
+// readEvent reads unmasked text frame sent by server
+func (c *testClient) readEvent(t *testing.T) (res map[string]interface{}) {
+	c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != 0x80|opText {
+		t.Fatalf("Expected text frame, got %x", header[0])
+	}
+	length := int(header[1])
+	if length == 126 {
+		var extended [2]byte
+		io.ReadFull(c.reader, extended[:])
+		length = int(extended[0])<<8 | int(extended[1])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(payload, &res); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+// writeFrame writes masked frame like a browser
+func (c *testClient) writeFrame(opcode byte, payload []byte) {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i := range payload {
+		frame = append(frame, payload[i]^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+func TestOptions(t *testing.T) {
+	_, ts := newTestServer(t)
+	var options []option
+	if status := request(t, http.MethodGet, ts.URL+"/options", "", &options); status != http.StatusOK {
+		t.Fatalf("Unexpected status %d", status)
+	}
+	if len(options) == 0 || options[0].Name != "Hash" || options[0].Type != "spin" || options[0].Value != 4.0 || options[0].Max == nil {
+		t.Errorf("Unexpected options %+v", options)
+	}
+	if status := request(t, http.MethodPost, ts.URL+"/options", "", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected method to be rejected, got %d", status)
+	}
+}
+
+func TestForbiddenRequests(t *testing.T) {
+	_, ts := newTestServer(t)
+	for _, test := range []struct {
+		origin, contentType string
+		status              int
+	}{
+		{"http://example.com", "application/json", http.StatusForbidden},
+		{"http://localhost.example.com", "application/json", http.StatusForbidden},
+		{"http://localhost:8080", "text/plain", http.StatusUnsupportedMediaType},
+		{"", "", http.StatusUnsupportedMediaType},
+		{"http://127.0.0.1:8080", "application/json; charset=utf-8", http.StatusAccepted},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/go", strings.NewReader(`{"depth": 1}`))
+		req.Header.Set("Origin", test.origin)
+		req.Header.Set("Content-Type", test.contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("Origin %q, content type %q: expected %d, got %d", test.origin, test.contentType, test.status, resp.StatusCode)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/ws", nil)
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected WebSocket from other origin to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	_, ts := newTestServer(t)
+	var res map[string]string
+	request(t, http.MethodPost, ts.URL+"/position", `{"moves": ["e2e4", "c7c5"]}`, &res)
+	if expected := "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 1"; res["fen"] != expected {
+		t.Errorf("Expected %s, got %s", expected, res["fen"])
+	}
+	for _, body := range []string{`{"fen": "invalid"}`, `{"moves": ["e2e5"]}`, `{`} {
+		if status := request(t, http.MethodPost, ts.URL+"/position", body, &res); status != http.StatusBadRequest || res["error"] == "" {
+			t.Errorf("Expected error for %s, got %d %v", body, status, res)
+		}
+	}
+	request(t, http.MethodGet, ts.URL+"/position", "", &res)
+	if !strings.HasPrefix(res["fen"], "rnbqkbnr/pp1ppppp/8/2p5/4P3") {
+		t.Errorf("Invalid requests should not change position, got %s", res["fen"])
+	}
+}
+
+func TestSearch(t *testing.T) {
+	_, ts := newTestServer(t)
+	client := dialWebsocket(t, ts)
+	defer client.conn.Close()
+
+	request(t, http.MethodPost, ts.URL+"/position", `{"fen": "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1"}`, nil)
+	if status := request(t, http.MethodPost, ts.URL+"/go", `{"depth": 6}`, nil); status != http.StatusAccepted {
+		t.Fatalf("Unexpected status %d", status)
+	}
+	depth := 0.0
+	var event map[string]interface{}
+	for event = client.readEvent(t); event["type"] == "info"; event = client.readEvent(t) {
+		if event["depth"].(float64) <= depth {
+			t.Errorf("Expected increasing depths, got %v after %v", event["depth"], depth)
+		}
+		depth = event["depth"].(float64)
+	}
+	if event["type"] != "bestmove" || event["bestmove"] != "a1a8" || event["score"].(map[string]interface{})["mate"] != 1.0 {
+		t.Errorf("Unexpected result event %v", event)
+	}
+
+	var res struct {
+		Searching bool
+		Result    map[string]interface{}
+	}
+	request(t, http.MethodGet, ts.URL+"/result", "", &res)
+	if res.Searching || res.Result["bestmove"] != "a1a8" {
+		t.Errorf("Expected finished search, got %+v", res)
+	}
+
+	client.writeFrame(opPing, []byte("ping"))
+	if header, _ := client.reader.Peek(2); header[0] != 0x80|opPong {
+		t.Errorf("Expected pong, got %x", header)
+	}
+}
+
+func TestStop(t *testing.T) {
+	_, ts := newTestServer(t)
+	if status := request(t, http.MethodPost, ts.URL+"/go", `{"infinite": true}`, nil); status != http.StatusAccepted {
+		t.Fatalf("Unexpected status %d", status)
+	}
+	if status := request(t, http.MethodPost, ts.URL+"/go", `{"depth": 1}`, nil); status != http.StatusConflict {
+		t.Errorf("Expected conflict when search is in progress, got %d", status)
+	}
+	if status := request(t, http.MethodPost, ts.URL+"/position", `{}`, nil); status != http.StatusConflict {
+		t.Errorf("Expected conflict when search is in progress, got %d", status)
+	}
+	time.Sleep(100 * time.Millisecond)
+	var res struct {
+		Searching bool
+		Result    struct {
+			BestMove string
+			Depth    int
+		}
+	}
+	request(t, http.MethodPost, ts.URL+"/stop", "", &res)
+	if res.Searching || res.Result.BestMove == "" || res.Result.Depth == 0 {
+		t.Errorf("Expected result of stopped search, got %+v", res)
+	}
+}
@@ -0,0 +1,314 @@
+package tablebase
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	. "github.com/mhib/combusken/backend"
+	. "github.com/mhib/combusken/utils"
+)
+
+// Tables are generated by retrograde analysis.
+// Positions are resolved in order of increasing distance to mate,
+// predecessors of resolved positions are found by unmaking quiet moves.
+// Captures and promotions lead to already generated tables.
+// Positions after double pawn push that allow en passant capture are not stored in table,
+// they are resolved together with table positions as additional nodes.
+
+const (
+	// Position is not legal or is not canonical representative of symmetric positions
+	invalidPosition = 0xFF
+	// Side to move can reach a draw or win with capture or promotion
+	cannotLose = 0x80
+)
+
+const maxDistance = 254
+
+type generator struct {
+	*table
+	tables registry
+	result []byte
+	// Number of unresolved children that are not captures or promotions
+	counter []byte
+	// Minimal distance of a loss forced by captures and promotions
+	lossFloor []byte
+	seen      []uint32
+	stamp     uint32
+	buckets   [maxDistance + 1][]uint32
+	// Nodes with identifiers starting at table size
+	epNodes []epNode
+	epIds   map[epKey]int
+	// Identifiers of en passant nodes by index of position with the same pieces
+	epByIndex map[int][]int
+}
+
+type epNode struct {
+	pos   Position
+	preds []int
+}
+
+type epKey struct {
+	colours    [White + 1]uint64
+	pieces     [King + 1]uint64
+	sideToMove int
+	epSquare   int
+}
+
+// Generate creates all tables with at most pieceCount pieces in dir
+func Generate(dir string, pieceCount int, progress io.Writer) error {
+	generated := make(registry)
+	for _, t := range allTables(pieceCount) {
+		start := time.Now()
+		data, err := generateTable(t, generated)
+		if err != nil {
+			return err
+		}
+		t.data = data
+		generated.add(t)
+		if err := t.write(filepath.Join(dir, t.fileName())); err != nil {
+			return err
+		}
+		fmt.Fprintf(progress, "%s\t%d positions\t%d ms\n", t.name, t.size, time.Since(start).Milliseconds())
+	}
+	return nil
+}
+
+func generateTable(t *table, tables registry) ([]byte, error) {
+	g := &generator{
+		table:     t,
+		tables:    tables,
+		result:    make([]byte, t.size),
+		counter:   make([]byte, t.size),
+		lossFloor: make([]byte, t.size),
+		seen:      make([]uint32, t.size),
+		epIds:     make(map[epKey]int),
+		epByIndex: make(map[int][]int),
+	}
+	for idx := 0; idx < t.size; idx++ {
+		if err := g.initPosition(idx); err != nil {
+			return nil, err
+		}
+	}
+	// En passant nodes are appended while their predecessors are initialised
+	for id := t.size; id < len(g.result); id++ {
+		if err := g.initChildren(id, &g.epNodes[id-t.size].pos); err != nil {
+			return nil, err
+		}
+	}
+	for distance := 0; distance <= maxDistance; distance++ {
+		for i := 0; i < len(g.buckets[distance]); i++ {
+			g.resolve(int(g.buckets[distance][i]), distance)
+		}
+		g.buckets[distance] = nil
+	}
+	return g.result[:t.size], nil
+}
+
+func (g *generator) push(id, distance int) {
+	if distance <= maxDistance {
+		g.buckets[distance] = append(g.buckets[distance], uint32(id))
+	}
+}
+
+// hasEpCapture returns true if en passant capture is legal in position
+func hasEpCapture(pos *Position) bool {
+	if pos.EpSquare == 0 {
+		return false
+	}
+	var buffer [256]EvaledMove
+	for _, move := range buffer[:GenerateLegalNoisy(pos, buffer[:])] {
+		if move.Type() == EPCapture {
+			return true
+		}
+	}
+	return false
+}
+
+// epNode returns identifier of en passant node reached from pred
+func (g *generator) epNode(pos *Position, pred int) int {
+	key := epKey{pos.Colours, pos.Pieces, pos.SideToMove, pos.EpSquare}
+	if id, ok := g.epIds[key]; ok {
+		g.epNodes[id-g.size].preds = append(g.epNodes[id-g.size].preds, pred)
+		return id
+	}
+	id := len(g.result)
+	g.result = append(g.result, drawValue)
+	g.counter = append(g.counter, 0)
+	g.lossFloor = append(g.lossFloor, 0)
+	g.seen = append(g.seen, 0)
+	g.epNodes = append(g.epNodes, epNode{pos: *pos, preds: []int{pred}})
+	g.epIds[key] = id
+	idx := g.indexOf(pos, false)
+	g.epByIndex[idx] = append(g.epByIndex[idx], id)
+	return id
+}
+
+func (g *generator) initPosition(idx int) error {
+	sideToMove, sq := g.decode(idx)
+	if g.index(sideToMove, &sq) != idx {
+		g.counter[idx] = invalidPosition
+		return nil
+	}
+	pos, ok := g.position(sideToMove, &sq)
+	if !ok || pos.IsSquareAttacked(sq[kingIndex(sideToMove^1)], sideToMove) {
+		g.counter[idx] = invalidPosition
+		return nil
+	}
+	return g.initChildren(idx, &pos)
+}
+
+func (g *generator) initChildren(id int, pos *Position) error {
+	var buffer [256]EvaledMove
+	var child Position
+	size := GenerateLegal(pos, buffer[:])
+	if size == 0 {
+		if pos.IsInCheck() {
+			g.push(id, 0)
+		} else {
+			g.counter[id] = cannotLose
+		}
+		return nil
+	}
+
+	g.stamp++
+	children := 0
+	winDistance := maxDistance + 1
+	for _, move := range buffer[:size] {
+		pos.MakeLegalMove(move.Move, &child)
+		if !move.IsCaptureOrPromotion() {
+			var childId int
+			if hasEpCapture(&child) {
+				childId = g.epNode(&child, id)
+			} else {
+				childId = g.indexOf(&child, false)
+			}
+			if g.seen[childId] != g.stamp {
+				g.seen[childId] = g.stamp
+				children++
+			}
+			continue
+		}
+		value, ok := g.tables.value(&child)
+		if !ok {
+			return fmt.Errorf("%s: table after %s is missing", g.name, move.Move.String())
+		}
+		if value == drawValue {
+			g.counter[id] |= cannotLose
+		} else if value&1 != 0 {
+			// Child loses
+			g.counter[id] |= cannotLose
+			if int(value) < winDistance {
+				winDistance = int(value)
+			}
+		} else if value > g.lossFloor[id] {
+			g.lossFloor[id] = value
+		}
+	}
+	g.counter[id] |= byte(children)
+	g.push(id, winDistance)
+	if children == 0 && g.counter[id]&cannotLose == 0 {
+		g.push(id, int(g.lossFloor[id]))
+	}
+	return nil
+}
+
+func (g *generator) resolve(id, distance int) {
+	if g.result[id] != drawValue {
+		return
+	}
+	g.result[id] = byte(distance + 1)
+	if id >= g.size {
+		for _, pred := range g.epNodes[id-g.size].preds {
+			g.update(pred, distance)
+		}
+		return
+	}
+	sideToMove, sq := g.decode(id)
+	g.predecessors(sideToMove, &sq, func(pred int) {
+		g.update(pred, distance)
+		// Quiet moves are the same after double pawn push
+		for _, epId := range g.epByIndex[pred] {
+			g.update(epId, distance)
+		}
+	})
+}
+
+// update propagates resolution of child at given distance to pred
+func (g *generator) update(pred, distance int) {
+	if g.counter[pred] == invalidPosition || g.result[pred] != drawValue {
+		return
+	}
+	if distance&1 == 0 {
+		// Position is lost, so predecessor wins
+		g.push(pred, distance+1)
+		return
+	}
+	g.counter[pred]--
+	if g.counter[pred] == 0 {
+		// All children are won for the opponent
+		g.push(pred, Max(distance+1, int(g.lossFloor[pred])))
+	}
+}
+
+// predecessors calls fn with every position that leads to position by a quiet move.
+// Double pawn pushes that allow en passant capture lead to en passant nodes and are skipped.
+// Every predecessor is reported once.
+func (g *generator) predecessors(sideToMove int, sq *squares, fn func(int)) {
+	mover := sideToMove ^ 1
+	var occupancy uint64
+	n := len(g.pieces) + 2
+	for i := 0; i < n; i++ {
+		occupancy |= SquareMask[sq[i]]
+	}
+	g.stamp++
+	for i := 0; i < n; i++ {
+		p := g.pieceAt(i)
+		if p.side != mover {
+			continue
+		}
+		to := sq[i]
+		var from uint64
+		switch p.kind {
+		case Pawn:
+			if p.side == White && Rank(to) >= RANK_3 && SquareMask[to-8]&occupancy == 0 {
+				from = SquareMask[to-8]
+				if Rank(to) == RANK_4 && SquareMask[to-16]&occupancy == 0 && !g.allowsEpCapture(sideToMove, sq, to) {
+					from |= SquareMask[to-16]
+				}
+			} else if p.side == Black && Rank(to) <= RANK_6 && SquareMask[to+8]&occupancy == 0 {
+				from = SquareMask[to+8]
+				if Rank(to) == RANK_5 && SquareMask[to+16]&occupancy == 0 && !g.allowsEpCapture(sideToMove, sq, to) {
+					from |= SquareMask[to+16]
+				}
+			}
+		case Knight:
+			from = KnightAttacks[to] &^ occupancy
+		case Bishop:
+			from = BishopAttacks(to, occupancy) &^ occupancy
+		case Rook:
+			from = RookAttacks(to, occupancy) &^ occupancy
+		case Queen:
+			from = QueenAttacks(to, occupancy) &^ occupancy
+		case King:
+			from = KingAttacks[to] &^ occupancy
+		}
+		for ; from != 0; from &= from - 1 {
+			sq[i] = BitScan(from)
+			pred := g.index(mover, sq)
+			if g.seen[pred] != g.stamp {
+				g.seen[pred] = g.stamp
+				fn(pred)
+			}
+		}
+		sq[i] = to
+	}
+}
+
+// allowsEpCapture returns true if en passant capture would be legal after double push of pawn to given square
+func (g *generator) allowsEpCapture(sideToMove int, sq *squares, pawn int) bool {
+	pos, _ := g.position(sideToMove, sq)
+	pos.EpSquare = pawn
+	return hasEpCapture(&pos)
+}
@@ -0,0 +1,345 @@
+package tablebase
+
+import (
+	"bufio"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	. "github.com/mhib/combusken/backend"
+)
+
+// Tables store distance to mate of every position of a material configuration.
+// Stronger side of a table is always white, positions with black being stronger
+// are probed after flipping the board.
+// Position index consists of side to move, white king square reduced by symmetry,
+// black king square and squares of remaining pieces.
+
+const maxPieces = 4
+
+const tableMagic = "CBTB"
+const tableVersion = 1
+const tableExtension = ".ctb"
+
+type piece struct {
+	side int
+	kind int
+}
+
+// Square of white king is reduced to a single region with board symmetries.
+// Pawnless tables use all 8 symmetries, tables with pawns only mirror files.
+var symmetry [8][64]int
+var regionIndex [2][64]int
+var regionSquares [2][]int
+
+func init() {
+	for s := range symmetry {
+		for sq := 0; sq < 64; sq++ {
+			res := sq
+			if s&1 != 0 {
+				res ^= 7
+			}
+			if s&2 != 0 {
+				res ^= 56
+			}
+			if s&4 != 0 {
+				res = File(res)<<3 | Rank(res)
+			}
+			symmetry[s][sq] = res
+		}
+	}
+	for sq := 0; sq < 64; sq++ {
+		regionIndex[0][sq] = -1
+		regionIndex[1][sq] = -1
+		// Triangle A1-D1-D4 for pawnless tables
+		if File(sq) <= FILE_D && Rank(sq) <= File(sq) {
+			regionIndex[0][sq] = len(regionSquares[0])
+			regionSquares[0] = append(regionSquares[0], sq)
+		}
+		// Files A-D for tables with pawns
+		if File(sq) <= FILE_D {
+			regionIndex[1][sq] = len(regionSquares[1])
+			regionSquares[1] = append(regionSquares[1], sq)
+		}
+	}
+}
+
+type table struct {
+	name   string
+	pieces []piece
+	// 1 if there is a pawn in the table
+	pawns      int
+	symmetries int
+	half       int
+	size       int
+
+	path string
+	once sync.Once
+	data []byte
+}
+
+func newTable(pieces []piece) *table {
+	t := &table{pieces: pieces, symmetries: 8}
+	t.name = tableName(pieces)
+	for _, p := range pieces {
+		if p.kind == Pawn {
+			t.pawns = 1
+			t.symmetries = 2
+		}
+	}
+	t.half = len(regionSquares[t.pawns])
+	for i := 0; i < len(pieces)+1; i++ {
+		t.half *= 64
+	}
+	t.size = 2 * t.half
+	return t
+}
+
+const pieceLetters = "PNBRQK"
+
+func tableName(pieces []piece) string {
+	var white, black strings.Builder
+	white.WriteByte('K')
+	black.WriteByte('K')
+	for _, p := range pieces {
+		if p.side == White {
+			white.WriteByte(pieceLetters[p.kind])
+		} else {
+			black.WriteByte(pieceLetters[p.kind])
+		}
+	}
+	return white.String() + "v" + black.String()
+}
+
+// allTables returns material configurations with at most maxPieces pieces.
+// Tables are ordered so every table is preceded by tables its captures and promotions lead to.
+func allTables(pieceCount int) (res []*table) {
+	kinds := []int{Queen, Rook, Bishop, Knight, Pawn}
+	var materials [][]piece
+	if pieceCount >= 3 {
+		for _, kind := range kinds {
+			materials = append(materials, []piece{{White, kind}})
+		}
+	}
+	if pieceCount >= 4 {
+		for i, first := range kinds {
+			for _, second := range kinds[i:] {
+				materials = append(materials, []piece{{White, first}, {White, second}})
+			}
+		}
+		for i, first := range kinds {
+			for _, second := range kinds[i:] {
+				materials = append(materials, []piece{{White, first}, {Black, second}})
+			}
+		}
+	}
+	for pieces := 1; pieces <= 2; pieces++ {
+		for pawns := 0; pawns <= 2; pawns++ {
+			for _, material := range materials {
+				if len(material) != pieces || countPawns(material) != pawns {
+					continue
+				}
+				res = append(res, newTable(material))
+			}
+		}
+	}
+	return
+}
+
+func countPawns(pieces []piece) (res int) {
+	for _, p := range pieces {
+		if p.kind == Pawn {
+			res++
+		}
+	}
+	return
+}
+
+// materialKey packs number of every piece type of both sides
+func materialKey(pieces []piece, mirrored bool) (key uint64) {
+	for _, p := range pieces {
+		side := p.side
+		if mirrored {
+			side ^= 1
+		}
+		key += 1 << uint(4*(side*King+p.kind))
+	}
+	return
+}
+
+func positionMaterialKey(pos *Position) (key uint64) {
+	for side := Black; side <= White; side++ {
+		for kind := Pawn; kind <= Queen; kind++ {
+			key |= uint64(PopCount(pos.Colours[side]&pos.Pieces[kind])) << uint(4*(side*King+kind))
+		}
+	}
+	return
+}
+
+// squares holds white king, black king and remaining pieces in table order
+type squares [maxPieces]int
+
+func (t *table) pieceAt(i int) piece {
+	switch i {
+	case 0:
+		return piece{White, King}
+	case 1:
+		return piece{Black, King}
+	}
+	return t.pieces[i-2]
+}
+
+func kingIndex(side int) int {
+	return 1 - side
+}
+
+// index returns index of position, that is the same for all symmetric positions
+func (t *table) index(sideToMove int, sq *squares) int {
+	n := len(t.pieces) + 2
+	best := -1
+	var tmp squares
+	for s := 0; s < t.symmetries; s++ {
+		region := regionIndex[t.pawns][symmetry[s][sq[0]]]
+		if region < 0 {
+			continue
+		}
+		for i := 1; i < n; i++ {
+			tmp[i] = symmetry[s][sq[i]]
+		}
+		// Identical pieces are sorted by square
+		if n == 4 && t.pieces[0] == t.pieces[1] && tmp[2] > tmp[3] {
+			tmp[2], tmp[3] = tmp[3], tmp[2]
+		}
+		raw := region
+		for i := 1; i < n; i++ {
+			raw = raw*64 + tmp[i]
+		}
+		if best < 0 || raw < best {
+			best = raw
+		}
+	}
+	return sideToMove*t.half + best
+}
+
+func (t *table) decode(idx int) (sideToMove int, sq squares) {
+	sideToMove = idx / t.half
+	raw := idx % t.half
+	for i := len(t.pieces) + 1; i >= 1; i-- {
+		sq[i] = raw % 64
+		raw /= 64
+	}
+	sq[0] = regionSquares[t.pawns][raw]
+	return
+}
+
+// position builds position from squares, returns false if pieces overlap or pawns stand on the last ranks
+func (t *table) position(sideToMove int, sq *squares) (pos Position, ok bool) {
+	for i := 0; i < len(t.pieces)+2; i++ {
+		p := t.pieceAt(i)
+		mask := SquareMask[sq[i]]
+		if (pos.Colours[White]|pos.Colours[Black])&mask != 0 || (p.kind == Pawn && mask&PROMOTION_RANKS != 0) {
+			return pos, false
+		}
+		pos.Colours[p.side] |= mask
+		pos.Pieces[p.kind] |= mask
+	}
+	pos.SideToMove = sideToMove
+	pos.Flags = 0xF
+	pos.LastMove = NullMove
+	return pos, true
+}
+
+// indexOf returns index of position with the same material as the table
+func (t *table) indexOf(pos *Position, mirrored bool) int {
+	var sq squares
+	flip := 0
+	sideToMove := pos.SideToMove
+	if mirrored {
+		flip = 56
+		sideToMove ^= 1
+	}
+	for i := 0; i < len(t.pieces)+2; i++ {
+		p := t.pieceAt(i)
+		side := p.side
+		if mirrored {
+			side ^= 1
+		}
+		bb := pos.Pieces[p.kind] & pos.Colours[side]
+		if i > 2 && t.pieces[i-2] == t.pieces[i-3] {
+			// Second of identical pieces
+			sq[i] = BitScan(bb&(bb-1)) ^ flip
+		} else {
+			sq[i] = BitScan(bb) ^ flip
+		}
+	}
+	return t.index(sideToMove, &sq)
+}
+
+func (t *table) fileName() string {
+	return t.name + tableExtension
+}
+
+func (t *table) write(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	writer.WriteString(tableMagic)
+	writer.WriteByte(tableVersion)
+	binary.Write(writer, binary.LittleEndian, uint32(len(t.data)))
+	compressor, err := flate.NewWriter(writer, flate.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err = compressor.Write(t.data); err != nil {
+		return err
+	}
+	if err = compressor.Close(); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func (t *table) read(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	header := make([]byte, len(tableMagic)+1)
+	if _, err = io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(tableMagic)]) != tableMagic || header[len(tableMagic)] != tableVersion {
+		return nil, errors.New("Invalid tablebase file " + path)
+	}
+	var size uint32
+	if err = binary.Read(reader, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	if int(size) != t.size {
+		return nil, errors.New("Invalid tablebase size " + path)
+	}
+	data := make([]byte, size)
+	if _, err = io.ReadFull(flate.NewReader(reader), data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// load reads table from disk on first use
+func (t *table) load() []byte {
+	t.once.Do(func() {
+		if t.data == nil && t.path != "" {
+			t.data, _ = t.read(t.path)
+		}
+	})
+	return t.data
+}
@@ -0,0 +1,236 @@
+// Package tablebase generates and probes distance to mate tablebases
+// for endgames with up to 4 pieces without external dependencies.
+// Probing methods have the same shape as functions in fathom package,
+// so they can be used when Syzygy tablebases are unavailable.
+// Unlike Syzygy tables, every Tablebase holds its own tables.
+package tablebase
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/mhib/combusken/backend"
+	. "github.com/mhib/combusken/utils"
+)
+
+const (
+	TB_LOSS = iota
+	TB_BLESSED_LOSS
+	TB_DRAW
+	TB_CURSED_WIN
+	TB_WIN
+)
+
+const TB_RESULT_FAILED = int64(0xFFFFFFFF)
+
+// Every table entry holds distance to mate in plies increased by one.
+// Even value means that side to move wins, odd that it gets mated.
+// Zero means that position is a draw.
+const drawValue = 0
+
+type tableEntry struct {
+	*table
+	mirrored bool
+}
+
+type registry map[uint64]tableEntry
+
+func (r registry) add(t *table) {
+	r[materialKey(t.pieces, false)] = tableEntry{t, false}
+	if key := materialKey(t.pieces, true); key != materialKey(t.pieces, false) {
+		r[key] = tableEntry{t, true}
+	}
+}
+
+// value returns table entry of position, bare kings are a draw.
+// Positions that allow en passant capture are not stored in tables,
+// so their values are backed up from children.
+func (r registry) value(pos *Position) (byte, bool) {
+	if hasEpCapture(pos) {
+		return r.backedUpValue(pos)
+	}
+	if pos.Colours[White]|pos.Colours[Black] == pos.Pieces[King] {
+		return drawValue, true
+	}
+	entry, ok := r[positionMaterialKey(pos)]
+	if !ok {
+		return 0, false
+	}
+	data := entry.load()
+	if data == nil {
+		return 0, false
+	}
+	return data[entry.indexOf(pos, entry.mirrored)], true
+}
+
+// backedUpValue returns value of position computed from values of its children
+func (r registry) backedUpValue(pos *Position) (byte, bool) {
+	var buffer [256]EvaledMove
+	var child Position
+	size := GenerateLegal(pos, buffer[:])
+	if size == 0 {
+		if pos.IsInCheck() {
+			return 1, true
+		}
+		return drawValue, true
+	}
+	bestWin, worstLoss, draw := maxDistance+1, 0, false
+	for _, move := range buffer[:size] {
+		pos.MakeLegalMove(move.Move, &child)
+		value, ok := r.value(&child)
+		if !ok {
+			return 0, false
+		}
+		if value == drawValue {
+			draw = true
+		} else if value&1 != 0 {
+			bestWin = Min(bestWin, int(value))
+		} else {
+			worstLoss = Max(worstLoss, int(value))
+		}
+	}
+	if bestWin <= maxDistance {
+		return byte(bestWin + 1), true
+	} else if draw {
+		return drawValue, true
+	}
+	return byte(worstLoss + 1), true
+}
+
+// Tablebase holds tables registered from path list, zero value has no tables
+type Tablebase struct {
+	tables registry
+	// The largest number of pieces in registered tables
+	maxPieceCount int
+}
+
+// SetPath registers tables found in directories from path list
+func (tb *Tablebase) SetPath(path string) {
+	tb.Clear()
+	for _, dir := range filepath.SplitList(path) {
+		for _, t := range allTables(maxPieces) {
+			if _, ok := tb.tables[materialKey(t.pieces, false)]; ok {
+				continue
+			}
+			tablePath := filepath.Join(dir, t.fileName())
+			if _, err := os.Stat(tablePath); err != nil {
+				continue
+			}
+			t.path = tablePath
+			tb.tables.add(t)
+			tb.maxPieceCount = Max(tb.maxPieceCount, len(t.pieces)+2)
+		}
+	}
+}
+
+func (tb *Tablebase) Clear() {
+	tb.tables = make(registry)
+	tb.maxPieceCount = 0
+}
+
+// MaxPieceCount returns the largest number of pieces in registered tables, 0 if there are none
+func (tb *Tablebase) MaxPieceCount() int {
+	return tb.maxPieceCount
+}
+
+func wdl(value byte) int64 {
+	if value == drawValue {
+		return TB_DRAW
+	} else if value&1 == 0 {
+		return TB_WIN
+	}
+	return TB_LOSS
+}
+
+func (tb *Tablebase) ProbeWDL(pos *Position, depth int) int64 {
+	value, ok := tb.tables.value(pos)
+	if !ok {
+		return TB_RESULT_FAILED
+	}
+	return wdl(value)
+}
+
+// ProbeDTM returns result and distance to mate in plies
+func (tb *Tablebase) ProbeDTM(pos *Position) (bool, int64, int) {
+	value, ok := tb.tables.value(pos)
+	if !ok || value == drawValue {
+		return ok, TB_DRAW, 0
+	}
+	return true, wdl(value), int(value) - 1
+}
+
+func (tb *Tablebase) IsWDLProbeable(pos *Position, depth, minProbeDepth int) bool {
+	return tb.maxPieceCount != 0 &&
+		pos.FiftyMove == 0 &&
+		pos.EpSquare == 0 &&
+		pos.Flags == 0xF &&
+		tb.depthCardinalityCheck(pos, depth, minProbeDepth)
+}
+
+func (tb *Tablebase) depthCardinalityCheck(pos *Position, depth, minProbeDepth int) bool {
+	cardinality := PopCount(pos.Colours[White] | pos.Colours[Black])
+	return cardinality < tb.maxPieceCount || (cardinality == tb.maxPieceCount && depth >= minProbeDepth)
+}
+
+func (tb *Tablebase) IsDTZProbeable(pos *Position) bool {
+	return tb.maxPieceCount != 0 && pos.Flags == 0xF && PopCount(pos.Colours[White]|pos.Colours[Black]) <= tb.maxPieceCount
+}
+
+// ProbeDTZ selects move that leads to the fastest mate or the slowest loss.
+// Returned distance is distance to mate in plies.
+func (tb *Tablebase) ProbeDTZ(pos *Position, moves []EvaledMove) (bool, Move, int, int) {
+	bestMove := NullMove
+	bestScore := 0
+	var child Position
+	for _, move := range moves {
+		pos.MakeLegalMove(move.Move, &child)
+		ok, result, dtm := tb.ProbeDTM(&child)
+		if !ok {
+			return false, NullMove, 0, 0
+		}
+		// Score of child from side to move perspective, faster wins are better
+		var score int
+		if result == TB_LOSS {
+			score = 1000 - dtm
+		} else if result == TB_WIN {
+			score = -1000 + dtm
+		}
+		if bestMove == NullMove || score > bestScore {
+			bestMove = move.Move
+			bestScore = score
+		}
+	}
+	if bestMove == NullMove {
+		return false, NullMove, 0, 0
+	}
+	if bestScore > 0 {
+		return true, bestMove, TB_WIN, 1000 - bestScore + 1
+	} else if bestScore < 0 {
+		return true, bestMove, TB_LOSS, bestScore + 1000 + 1
+	}
+	return true, bestMove, TB_DRAW, 0
+}
+
+// ProbeRoot ranks root moves by distance to mate.
+// Rank and score of a move is its mate score from side to move perspective,
+// so only the fastest wins and the slowest losses get the best rank.
+// Fifty move rule is not taken into account.
+func (tb *Tablebase) ProbeRoot(pos *Position, moves []EvaledMove, ranks, scores []int) bool {
+	var child Position
+	for i, move := range moves {
+		pos.MakeLegalMove(move.Move, &child)
+		ok, result, dtm := tb.ProbeDTM(&child)
+		if !ok {
+			return false
+		}
+		if result == TB_LOSS {
+			ranks[i] = Mate - dtm - 1
+		} else if result == TB_WIN {
+			ranks[i] = -Mate + dtm + 1
+		} else {
+			ranks[i] = 0
+		}
+		scores[i] = ranks[i]
+	}
+	return len(moves) > 0
+}
@@ -0,0 +1,224 @@
+package tablebase
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/evaluation"
+)
+
+// validPositions calls fn with every legal canonical position of table
+func validPositions(t *table, fn func(idx int, pos *Position)) {
+	for idx := 0; idx < t.size; idx++ {
+		sideToMove, sq := t.decode(idx)
+		if t.index(sideToMove, &sq) != idx {
+			continue
+		}
+		pos, ok := t.position(sideToMove, &sq)
+		if !ok || pos.IsSquareAttacked(sq[kingIndex(sideToMove^1)], sideToMove) {
+			continue
+		}
+		fn(idx, &pos)
+	}
+}
+
+// verifyTable checks that every entry is consistent with entries of its children
+func verifyTable(tst *testing.T, t *table, tables registry) {
+	errors := 0
+	validPositions(t, func(idx int, pos *Position) {
+		expected, ok := tables.backedUpValue(pos)
+		if !ok {
+			tst.Fatalf("%s: index %d has child in missing table", t.name, idx)
+		}
+		if t.data[idx] != expected && errors < 10 {
+			errors++
+			tst.Errorf("%s: index %d has value %d, expected %d", t.name, idx, t.data[idx], expected)
+		}
+	})
+}
+
+func longestMate(t *table) (res int) {
+	for idx := t.half; idx < t.size; idx++ {
+		if value := int(t.data[idx]); value != drawValue && value&1 == 0 && value-1 > res {
+			res = value - 1
+		}
+	}
+	return
+}
+
+// generateTables generates 3 piece tables in a temporary directory and registers them in returned tablebase
+func generateTables(t *testing.T) (string, *Tablebase) {
+	dir, err := ioutil.TempDir("", "tablebase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Generate(dir, 3, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var tb Tablebase
+	tb.SetPath(dir)
+	return dir, &tb
+}
+
+func TestGenerate(t *testing.T) {
+	dir, tb := generateTables(t)
+	defer os.RemoveAll(dir)
+	if tb.MaxPieceCount() != 3 {
+		t.Fatalf("Expected 3 piece tables, got %d", tb.MaxPieceCount())
+	}
+
+	for _, entry := range tb.tables {
+		entry.load()
+		verifyTable(t, entry.table, tb.tables)
+	}
+
+	// Longest mates with white to move
+	for _, test := range []struct {
+		pieces []piece
+		plies  int
+	}{
+		{[]piece{{White, Queen}}, 19},
+		{[]piece{{White, Rook}}, 31},
+		{[]piece{{White, Pawn}}, 55},
+		{[]piece{{White, Bishop}}, 0},
+		{[]piece{{White, Knight}}, 0},
+	} {
+		entry := tb.tables[materialKey(test.pieces, false)]
+		if res := longestMate(entry.table); res != test.plies {
+			t.Errorf("%s: longest mate %d, expected %d", entry.name, res, test.plies)
+		}
+	}
+
+	// Table agrees with KPK bitbase
+	kpk := tb.tables[materialKey([]piece{{White, Pawn}}, false)]
+	validPositions(kpk.table, func(idx int, pos *Position) {
+		if (tb.ProbeWDL(pos, 0) == TB_DRAW) != evaluation.IsKPKDraw(pos) {
+			t.Errorf("KPK index %d differs from bitbase", idx)
+		}
+	})
+}
+
+func TestProbe(t *testing.T) {
+	dir, tb := generateTables(t)
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		fen  string
+		wdl  int64
+		dtm  int
+		best string
+	}{
+		{"7k/8/6K1/8/8/8/8/1Q6 w - - 0 1", TB_WIN, 1, "b1b8"},
+		{"1q6/8/8/8/8/6k1/8/7K b - - 0 1", TB_WIN, 1, "b8b1"},
+		{"7k/Q7/6K1/8/8/8/8/8 b - - 0 1", TB_LOSS, 2, "h8g8"},
+		{"k7/8/8/8/P7/8/8/K7 w - - 0 1", TB_DRAW, 0, ""},
+		{"8/8/8/3k4/8/8/8/3KB3 w - - 0 1", TB_DRAW, 0, ""},
+	} {
+		pos := ParseFen(test.fen)
+		if !tb.IsWDLProbeable(&pos, 0, 0) || !tb.IsDTZProbeable(&pos) {
+			t.Errorf("%s: position should be probeable", test.fen)
+			continue
+		}
+		if res := tb.ProbeWDL(&pos, 0); res != test.wdl {
+			t.Errorf("%s: expected wdl %d, got %d", test.fen, test.wdl, res)
+		}
+		ok, move, wdl, dtm := tb.ProbeDTZ(&pos, GenerateAllLegalMoves(&pos))
+		if !ok || wdl != int(test.wdl) || dtm != test.dtm {
+			t.Errorf("%s: expected %d %d, got %v %d %d", test.fen, test.wdl, test.dtm, ok, wdl, dtm)
+		}
+		if test.best != "" && move.String() != test.best {
+			t.Errorf("%s: expected move %s, got %s", test.fen, test.best, move.String())
+		}
+	}
+
+	pos := ParseFen("8/8/8/3k4/8/8/3R4/3KB3 w - - 0 1")
+	if tb.IsWDLProbeable(&pos, 0, 0) || tb.ProbeWDL(&pos, 0) != TB_RESULT_FAILED {
+		t.Error("Position with too many pieces should not be probeable")
+	}
+
+	// Tables are not shared with other tablebases
+	var other Tablebase
+	pos = ParseFen("7k/8/6K1/8/8/8/8/1Q6 w - - 0 1")
+	if other.IsWDLProbeable(&pos, 0, 0) || other.ProbeWDL(&pos, 0) != TB_RESULT_FAILED {
+		t.Error("Empty tablebase should not probe positions")
+	}
+	other.SetPath(dir)
+	tb.Clear()
+	if tb.IsDTZProbeable(&pos) || !other.IsDTZProbeable(&pos) {
+		t.Error("Clearing tablebase should not affect other tablebases")
+	}
+}
+
+// generateFourPieceTables generates 4 piece tables in given order after 3 piece tables
+func generateFourPieceTables(t *testing.T, pieces ...[]piece) registry {
+	tables := make(registry)
+	all := allTables(3)
+	for _, p := range pieces {
+		all = append(all, newTable(p))
+	}
+	for _, table := range all {
+		data, err := generateTable(table, tables)
+		if err != nil {
+			t.Fatal(err)
+		}
+		table.data = data
+		tables.add(table)
+	}
+	return tables
+}
+
+func TestGenerateFourPieces(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	krq := []piece{{White, Queen}, {Black, Rook}}
+	tables := generateFourPieceTables(t, krq)
+	table := tables[materialKey(krq, false)].table
+	verifyTable(t, table, tables)
+	// Queen against rook is won in at most 35 moves
+	if res := longestMate(table); res != 69 {
+		t.Errorf("Longest KQvKR mate %d, expected 69", res)
+	}
+}
+
+func TestGenerateEnPassant(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	tables := generateFourPieceTables(t)
+	kpkp := newTable([]piece{{White, Pawn}, {Black, Pawn}})
+	if _, err := generateTable(kpkp, tables); err == nil {
+		t.Error("Expected error when tables of promotions are missing")
+	}
+
+	// Promotions are scored as draws, so only captures of pawns decide
+	for _, kind := range []int{Queen, Rook, Bishop, Knight} {
+		promotion := newTable([]piece{{White, kind}, {Black, Pawn}})
+		promotion.data = make([]byte, promotion.size)
+		tables.add(promotion)
+	}
+	data, err := generateTable(kpkp, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kpkp.data = data
+	tables.add(kpkp)
+	verifyTable(t, kpkp, tables)
+
+	// White wins only by capturing en passant
+	pos := ParseFen("8/8/8/Pp6/8/8/8/k1K5 w - b6 0 1")
+	if value, ok := tables.value(&pos); !ok || value == drawValue || value&1 != 0 {
+		t.Errorf("Expected win after en passant capture, got %d", value)
+	}
+	pos.EpSquare = 0
+	if value, _ := tables.value(&pos); value != drawValue {
+		t.Errorf("Expected draw without en passant capture, got %d", value)
+	}
+	// Black avoids double push
+	parent := ParseFen("8/1p6/8/P7/8/8/8/k1K5 b - - 0 1")
+	if value, _ := tables.value(&parent); value != drawValue {
+		t.Errorf("Expected draw, got %d", value)
+	}
+}
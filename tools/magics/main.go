@@ -0,0 +1,48 @@
+// Generates backend/magics.go with magic numbers for sliding pieces attacks.
+// Run with `go generate ./backend`.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"math/rand"
+
+	"github.com/mhib/combusken/backend"
+)
+
+func writeMagics(buf *bytes.Buffer, name string, magics [64]uint64) {
+	fmt.Fprintf(buf, "var %s = [64]uint64{\n", name)
+	for i, magic := range magics {
+		fmt.Fprintf(buf, "0x%016X,", magic)
+		if i%4 == 3 {
+			buf.WriteString("\n")
+		}
+	}
+	buf.WriteString("}\n\n")
+}
+
+func main() {
+	output := flag.String("o", "magics.go", "output file")
+	seed := flag.Int64("seed", 0, "seed of pseudo-random number generator")
+	flag.Parse()
+
+	rookMagics, bishopMagics := backend.FindMagics(rand.New(rand.NewSource(*seed)))
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by tools/magics; DO NOT EDIT.\n\n")
+	buf.WriteString("package backend\n\n")
+	writeMagics(&buf, "rookMagicIndex", rookMagics)
+	writeMagics(&buf, "bishopMagicIndex", bishopMagics)
+
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile(*output, source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
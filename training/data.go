@@ -0,0 +1,64 @@
+package training
+
+import (
+	"math"
+
+	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/dataset"
+	"github.com/mhib/combusken/nnue"
+)
+
+// Weight of search score in target when line contains score
+const scoreWeight = 0.5
+
+type sample struct {
+	// Active features of white and black perspective
+	features   [2][]int32
+	sideToMove int
+	// Expected result from side to move perspective
+	target float32
+}
+
+// newSample converts record to sample, search score of record is blended with game result
+func newSample(record *dataset.Record) sample {
+	target := record.WhiteResult()
+	if record.Score != dataset.NoScore {
+		target = scoreWeight*sigmoid(float64(record.Score)/nnue.EvalScale) + (1-scoreWeight)*target
+	}
+	pos := &record.Position
+	res := sample{features: positionFeatures(pos), sideToMove: pos.SideToMove, target: float32(target)}
+	if pos.SideToMove == Black {
+		res.target = 1 - res.target
+	}
+	return res
+}
+
+func positionFeatures(pos *Position) (res [2][]int32) {
+	for perspective := Black; perspective <= White; perspective++ {
+		kingSquare := BitScan(pos.Pieces[King] & pos.Colours[perspective])
+		for side := Black; side <= White; side++ {
+			for kind := Pawn; kind < King; kind++ {
+				for fromBB := pos.Pieces[kind] & pos.Colours[side]; fromBB != 0; fromBB &= fromBB - 1 {
+					res[perspective] = append(res[perspective], int32(nnue.FeatureIndex(perspective, kingSquare, side, kind, BitScan(fromBB))))
+				}
+			}
+		}
+	}
+	return
+}
+
+// loadSamples reads samples from binary or text file, every validationInterval-th sample is used for validation
+func loadSamples(path string, validationInterval int) (training, validation []sample, err error) {
+	err = dataset.ReadFile(path, func(record *dataset.Record) {
+		if (len(training)+len(validation)+1)%validationInterval == 0 {
+			validation = append(validation, newSample(record))
+		} else {
+			training = append(training, newSample(record))
+		}
+	})
+	return
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
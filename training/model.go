@@ -0,0 +1,222 @@
+package training
+
+import (
+	"encoding/gob"
+	"math"
+	"math/rand"
+	"os"
+
+	"github.com/mhib/combusken/nnue"
+)
+
+// model is floating point version of nnue.Network.
+// Activations are clipped to [0, 1] and output is scaled so sigmoid of it is expected result.
+type model struct {
+	HalfDimensions   int
+	HiddenDimensions int
+	FeatureBiases    []float32
+	FeatureWeights   []float32
+	HiddenBiases     []float32
+	HiddenWeights    []float32
+	OutputBias       []float32
+	OutputWeights    []float32
+}
+
+func newModel(halfDimensions, hiddenDimensions int, rng *rand.Rand) *model {
+	m := &model{
+		HalfDimensions:   halfDimensions,
+		HiddenDimensions: hiddenDimensions,
+		FeatureBiases:    make([]float32, halfDimensions),
+		FeatureWeights:   make([]float32, nnue.FeatureCount*halfDimensions),
+		HiddenBiases:     make([]float32, hiddenDimensions),
+		HiddenWeights:    make([]float32, hiddenDimensions*2*halfDimensions),
+		OutputBias:       make([]float32, 1),
+		OutputWeights:    make([]float32, hiddenDimensions),
+	}
+	uniform := func(values []float32, limit float64) {
+		for i := range values {
+			values[i] = float32((rng.Float64()*2 - 1) * limit)
+		}
+	}
+	for i := range m.FeatureBiases {
+		m.FeatureBiases[i] = 0.25
+	}
+	uniform(m.FeatureWeights, 0.1)
+	uniform(m.HiddenWeights, 1/math.Sqrt(float64(2*halfDimensions)))
+	uniform(m.OutputWeights, 1/math.Sqrt(float64(hiddenDimensions)))
+	return m
+}
+
+// parameters returns parameters in the same order as gradients
+func (m *model) parameters() [][]float32 {
+	return [][]float32{m.FeatureBiases, m.FeatureWeights, m.HiddenBiases, m.HiddenWeights, m.OutputBias, m.OutputWeights}
+}
+
+// activations holds intermediate values of forward pass
+type activations struct {
+	accumulators [2][]float32
+	input        []float32
+	hidden       []float32
+}
+
+func (m *model) newActivations() *activations {
+	return &activations{
+		accumulators: [2][]float32{make([]float32, m.HalfDimensions), make([]float32, m.HalfDimensions)},
+		input:        make([]float32, 2*m.HalfDimensions),
+		hidden:       make([]float32, m.HiddenDimensions),
+	}
+}
+
+// forward returns network output from side to move perspective
+func (m *model) forward(s *sample, a *activations) float32 {
+	half := m.HalfDimensions
+	for perspective := range a.accumulators {
+		acc := a.accumulators[perspective]
+		copy(acc, m.FeatureBiases)
+		for _, feature := range s.features[perspective] {
+			for i, weight := range m.FeatureWeights[int(feature)*half : (int(feature)+1)*half] {
+				acc[i] += weight
+			}
+		}
+	}
+	for i := 0; i < half; i++ {
+		a.input[i] = clip(a.accumulators[s.sideToMove][i])
+		a.input[half+i] = clip(a.accumulators[s.sideToMove^1][i])
+	}
+	output := m.OutputBias[0]
+	for neuron := range a.hidden {
+		sum := m.HiddenBiases[neuron]
+		for i, weight := range m.HiddenWeights[neuron*2*half : (neuron+1)*2*half] {
+			sum += weight * a.input[i]
+		}
+		a.hidden[neuron] = clip(sum)
+		output += m.OutputWeights[neuron] * a.hidden[neuron]
+	}
+	return output
+}
+
+// backward adds gradients of parameters for derivative of loss with respect to output
+func (m *model) backward(s *sample, a *activations, outputGradient float32, g *gradients) {
+	half := m.HalfDimensions
+	g.outputBias[0] += outputGradient
+	inputGradient := g.inputGradient
+	for i := range inputGradient {
+		inputGradient[i] = 0
+	}
+	for neuron, value := range a.hidden {
+		g.outputWeights[neuron] += outputGradient * value
+		if value <= 0 || value >= 1 {
+			continue
+		}
+		hiddenGradient := outputGradient * m.OutputWeights[neuron]
+		g.hiddenBiases[neuron] += hiddenGradient
+		weights := m.HiddenWeights[neuron*2*half : (neuron+1)*2*half]
+		weightGradients := g.hiddenWeights[neuron*2*half : (neuron+1)*2*half]
+		for i, input := range a.input {
+			weightGradients[i] += hiddenGradient * input
+			inputGradient[i] += hiddenGradient * weights[i]
+		}
+	}
+	for i := range inputGradient {
+		if a.input[i] <= 0 || a.input[i] >= 1 {
+			inputGradient[i] = 0
+		}
+	}
+	for perspective := range a.accumulators {
+		// Side to move accumulator is the first half of input
+		offset := 0
+		if perspective != s.sideToMove {
+			offset = half
+		}
+		accumulatorGradient := inputGradient[offset : offset+half]
+		for i, value := range accumulatorGradient {
+			g.featureBiases[i] += value
+		}
+		for _, feature := range s.features[perspective] {
+			g.touch(int(feature))
+			row := g.featureWeights[int(feature)*half : (int(feature)+1)*half]
+			for i, value := range accumulatorGradient {
+				row[i] += value
+			}
+		}
+	}
+}
+
+func clip(value float32) float32 {
+	if value < 0 {
+		return 0
+	} else if value > 1 {
+		return 1
+	}
+	return value
+}
+
+// quantize converts model to network used by the engine
+func (m *model) quantize() *nnue.Network {
+	n := nnue.NewNetwork(m.HalfDimensions, m.HiddenDimensions)
+	for i, value := range m.FeatureBiases {
+		n.FeatureBiases[i] = quantize16(value, nnue.ActivationScale)
+	}
+	for i, value := range m.FeatureWeights {
+		n.FeatureWeights[i] = quantize16(value, nnue.ActivationScale)
+	}
+	for i, value := range m.HiddenBiases {
+		n.HiddenBiases[i] = quantize32(value, nnue.ActivationScale*nnue.WeightScale)
+	}
+	for i, value := range m.HiddenWeights {
+		n.HiddenWeights[i] = quantize16(value, nnue.WeightScale)
+	}
+	n.OutputBias = quantize32(m.OutputBias[0], nnue.ActivationScale*nnue.WeightScale)
+	for i, value := range m.OutputWeights {
+		n.OutputWeights[i] = quantize16(value, nnue.WeightScale)
+	}
+	return n
+}
+
+func quantize16(value float32, scale float64) int16 {
+	return int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(float64(value)*scale))))
+}
+
+func quantize32(value float32, scale float64) int32 {
+	return int32(math.Max(math.MinInt32, math.Min(math.MaxInt32, math.Round(float64(value)*scale))))
+}
+
+// checkpoint holds state needed to resume training
+type checkpoint struct {
+	Epoch     int
+	Step      int
+	Model     *model
+	Moments   [][]float32
+	Variances [][]float32
+	// Validation loss of saved network
+	BestLoss float64
+}
+
+func saveCheckpoint(path string, c *checkpoint) error {
+	// Write to temporary file first, so interrupted save does not destroy previous checkpoint
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err = gob.NewEncoder(file).Encode(c); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var c checkpoint
+	if err = gob.NewDecoder(file).Decode(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
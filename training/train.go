@@ -0,0 +1,306 @@
+// Package training trains networks for nnue package on CPU.
+package training
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/mhib/combusken/nnue"
+)
+
+type Options struct {
+	DataPath    string
+	NetworkPath string
+	// Training state is saved after every epoch and training is resumed from it when it exists
+	CheckpointPath   string
+	Epochs           int
+	HalfDimensions   int
+	HiddenDimensions int
+	BatchSize        int
+	LearningRate     float64
+	Threads          int
+	Seed             int64
+	Progress         io.Writer
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Epochs:           10,
+		HalfDimensions:   64,
+		HiddenDimensions: 16,
+		BatchSize:        16384,
+		LearningRate:     0.001,
+		Threads:          runtime.NumCPU(),
+		Seed:             1,
+		Progress:         os.Stdout,
+	}
+}
+
+// Every validationInterval-th sample is not used in training
+const validationInterval = 20
+
+const (
+	beta1   = 0.9
+	beta2   = 0.999
+	epsilon = 1e-8
+)
+
+// gradients of a single worker
+type gradients struct {
+	featureBiases  []float32
+	featureWeights []float32
+	hiddenBiases   []float32
+	hiddenWeights  []float32
+	outputBias     []float32
+	outputWeights  []float32
+	// Features that have non zero gradient
+	touched     []int
+	touchedMark []bool
+	// Gradient of input of hidden layer for current sample
+	inputGradient []float32
+	loss          float64
+}
+
+func newGradients(m *model) *gradients {
+	return &gradients{
+		featureBiases:  make([]float32, len(m.FeatureBiases)),
+		featureWeights: make([]float32, len(m.FeatureWeights)),
+		hiddenBiases:   make([]float32, len(m.HiddenBiases)),
+		hiddenWeights:  make([]float32, len(m.HiddenWeights)),
+		outputBias:     make([]float32, 1),
+		outputWeights:  make([]float32, len(m.OutputWeights)),
+		touchedMark:    make([]bool, nnue.FeatureCount),
+		inputGradient:  make([]float32, 2*m.HalfDimensions),
+	}
+}
+
+func (g *gradients) touch(feature int) {
+	if !g.touchedMark[feature] {
+		g.touchedMark[feature] = true
+		g.touched = append(g.touched, feature)
+	}
+}
+
+// dense returns gradients of all parameters except feature weights in parameters order
+func (g *gradients) dense() [][]float32 {
+	return [][]float32{g.featureBiases, nil, g.hiddenBiases, g.hiddenWeights, g.outputBias, g.outputWeights}
+}
+
+func (g *gradients) clear(half int) {
+	for _, values := range g.dense() {
+		for i := range values {
+			values[i] = 0
+		}
+	}
+	for _, feature := range g.touched {
+		g.touchedMark[feature] = false
+		for i := feature * half; i < (feature+1)*half; i++ {
+			g.featureWeights[i] = 0
+		}
+	}
+	g.touched = g.touched[:0]
+	g.loss = 0
+}
+
+type trainer struct {
+	Options
+	model     *model
+	moments   [][]float32
+	variances [][]float32
+	step      int
+	workers   []*gradients
+	// Feature rows updated in current batch
+	updated     []int
+	updatedMark []bool
+}
+
+// Train trains network on samples from data file and saves it after every epoch with the best validation loss
+func Train(options Options) error {
+	if options.HalfDimensions <= 0 || options.HalfDimensions > nnue.MaxHalfDimensions || options.HiddenDimensions <= 0 {
+		return fmt.Errorf("Network dimensions must be in range 1-%d and positive", nnue.MaxHalfDimensions)
+	}
+	if options.Threads <= 0 || options.BatchSize <= 0 {
+		return errors.New("Number of threads and batch size must be positive")
+	}
+	trainingSamples, validationSamples, err := loadSamples(options.DataPath, validationInterval)
+	if err != nil {
+		return err
+	}
+	if len(trainingSamples) == 0 || len(validationSamples) == 0 {
+		return errors.New("Not enough samples")
+	}
+	fmt.Fprintf(options.Progress, "Training samples: %d, validation samples: %d\n", len(trainingSamples), len(validationSamples))
+
+	rng := rand.New(rand.NewSource(options.Seed))
+	t := &trainer{Options: options, updatedMark: make([]bool, nnue.FeatureCount)}
+	startEpoch := 0
+	bestLoss := math.Inf(1)
+	if c, err := loadCheckpoint(options.CheckpointPath); err == nil {
+		if c.Model.HalfDimensions != options.HalfDimensions || c.Model.HiddenDimensions != options.HiddenDimensions {
+			return errors.New("Checkpoint has different network dimensions")
+		}
+		t.model, t.moments, t.variances, t.step, startEpoch = c.Model, c.Moments, c.Variances, c.Step, c.Epoch
+		bestLoss = c.BestLoss
+		fmt.Fprintf(options.Progress, "Resuming from epoch %d\n", startEpoch)
+	} else if !os.IsNotExist(err) && options.CheckpointPath != "" {
+		return err
+	} else {
+		t.model = newModel(options.HalfDimensions, options.HiddenDimensions, rng)
+		for _, values := range t.model.parameters() {
+			t.moments = append(t.moments, make([]float32, len(values)))
+			t.variances = append(t.variances, make([]float32, len(values)))
+		}
+	}
+	for i := 0; i < options.Threads; i++ {
+		t.workers = append(t.workers, newGradients(t.model))
+	}
+
+	for epoch := startEpoch + 1; epoch <= options.Epochs; epoch++ {
+		start := time.Now()
+		rng.Shuffle(len(trainingSamples), func(i, j int) {
+			trainingSamples[i], trainingSamples[j] = trainingSamples[j], trainingSamples[i]
+		})
+		var trainingLoss float64
+		for batchStart := 0; batchStart < len(trainingSamples); batchStart += options.BatchSize {
+			batchEnd := batchStart + options.BatchSize
+			if batchEnd > len(trainingSamples) {
+				batchEnd = len(trainingSamples)
+			}
+			trainingLoss += t.trainBatch(trainingSamples[batchStart:batchEnd])
+		}
+		trainingLoss /= float64(len(trainingSamples))
+		validationLoss := t.validationLoss(validationSamples)
+		fmt.Fprintf(options.Progress, "Epoch %d training loss: %.6f validation loss: %.6f time: %v\n",
+			epoch, trainingLoss, validationLoss, time.Since(start).Round(time.Millisecond))
+
+		if validationLoss < bestLoss {
+			bestLoss = validationLoss
+			network := t.model.quantize()
+			if err = network.Save(options.NetworkPath); err != nil {
+				return err
+			}
+			fmt.Fprintf(options.Progress, "Saved network, quantized validation loss: %.6f\n", quantizedLoss(network, validationSamples))
+		}
+		// Checkpoint is saved after network, so resumed training does not overwrite a better network
+		if options.CheckpointPath != "" {
+			err = saveCheckpoint(options.CheckpointPath, &checkpoint{epoch, t.step, t.model, t.moments, t.variances, bestLoss})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// trainBatch updates model with gradients of batch and returns sum of losses
+func (t *trainer) trainBatch(batch []sample) float64 {
+	var wg sync.WaitGroup
+	for idx, worker := range t.workers {
+		wg.Add(1)
+		go func(idx int, g *gradients) {
+			defer wg.Done()
+			g.clear(t.model.HalfDimensions)
+			a := t.model.newActivations()
+			for i := idx; i < len(batch); i += len(t.workers) {
+				s := &batch[i]
+				prediction := float32(sigmoid(float64(t.model.forward(s, a))))
+				diff := prediction - s.target
+				g.loss += float64(diff * diff)
+				t.model.backward(s, a, 2*diff*prediction*(1-prediction)/float32(len(batch)), g)
+			}
+		}(idx, worker)
+	}
+	wg.Wait()
+
+	// Sum gradients of workers into the first one
+	sum := t.workers[0]
+	loss := sum.loss
+	for _, g := range t.workers[1:] {
+		loss += g.loss
+		for i, values := range g.dense() {
+			target := sum.dense()[i]
+			for j := range values {
+				target[j] += values[j]
+			}
+		}
+		for _, feature := range g.touched {
+			sum.touch(feature)
+			half := t.model.HalfDimensions
+			for i := feature * half; i < (feature+1)*half; i++ {
+				sum.featureWeights[i] += g.featureWeights[i]
+			}
+		}
+	}
+
+	t.step++
+	rate := t.LearningRate * math.Sqrt(1-math.Pow(beta2, float64(t.step))) / (1 - math.Pow(beta1, float64(t.step)))
+	parameters := t.model.parameters()
+	for i, values := range sum.dense() {
+		if values != nil {
+			t.adam(parameters[i], values, t.moments[i], t.variances[i], 0, len(values), rate)
+		}
+	}
+	// Feature weights are updated only for features present in batch
+	half := t.model.HalfDimensions
+	for _, feature := range sum.touched {
+		t.adam(t.model.FeatureWeights, sum.featureWeights, t.moments[1], t.variances[1], feature*half, (feature+1)*half, rate)
+	}
+	return loss
+}
+
+func (t *trainer) adam(values, gradient, moments, variances []float32, start, end int, rate float64) {
+	for i := start; i < end; i++ {
+		g := float64(gradient[i])
+		m := beta1*float64(moments[i]) + (1-beta1)*g
+		v := beta2*float64(variances[i]) + (1-beta2)*g*g
+		moments[i], variances[i] = float32(m), float32(v)
+		values[i] -= float32(rate * m / (math.Sqrt(v) + epsilon))
+	}
+}
+
+func (t *trainer) validationLoss(samples []sample) float64 {
+	losses := make([]float64, len(t.workers))
+	var wg sync.WaitGroup
+	for idx := range t.workers {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			a := t.model.newActivations()
+			for i := idx; i < len(samples); i += len(t.workers) {
+				diff := sigmoid(float64(t.model.forward(&samples[i], a))) - float64(samples[i].target)
+				losses[idx] += diff * diff
+			}
+		}(idx)
+	}
+	wg.Wait()
+	var sum float64
+	for _, loss := range losses {
+		sum += loss
+	}
+	return sum / float64(len(samples))
+}
+
+// quantizedLoss returns loss of network evaluation used by the engine
+func quantizedLoss(n *nnue.Network, samples []sample) float64 {
+	var sum float64
+	var acc nnue.Accumulator
+	for i := range samples {
+		for perspective := range samples[i].features {
+			acc.Reset(n, perspective)
+			for _, feature := range samples[i].features[perspective] {
+				acc.Add(n, perspective, int(feature))
+			}
+		}
+		score := n.Evaluate(&acc, samples[i].sideToMove)
+		diff := sigmoid(float64(score)/nnue.EvalScale) - float64(samples[i].target)
+		sum += diff * diff
+	}
+	return sum / float64(len(samples))
+}
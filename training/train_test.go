@@ -0,0 +1,178 @@
+package training
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/dataset"
+	"github.com/mhib/combusken/nnue"
+)
+
+func TestGradient(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	m := newModel(8, 4, rng)
+	record, ok := dataset.ParseLine("4k3/8/8/3q4/8/2N5/8/4K3 b - - 0 1;0-1")
+	if !ok {
+		t.Fatal("Could not parse record")
+	}
+	s := newSample(&record)
+	a := m.newActivations()
+	g := newGradients(m)
+	m.forward(&s, a)
+	m.backward(&s, a, 1, g)
+
+	parameters := m.parameters()
+	gradients := g.dense()
+	gradients[1] = g.featureWeights
+	const epsilon = 1e-3
+	for i, values := range parameters {
+		for j := range values {
+			if gradients[i][j] == 0 && rng.Intn(8) != 0 {
+				continue
+			}
+			original := values[j]
+			values[j] = original + epsilon
+			plus := m.forward(&s, a)
+			values[j] = original - epsilon
+			minus := m.forward(&s, a)
+			values[j] = original
+			numerical := (plus - minus) / (2 * epsilon)
+			if math.Abs(float64(numerical-gradients[i][j])) > 1e-2 {
+				t.Errorf("Parameter %d %d: expected gradient %f, got %f", i, j, numerical, gradients[i][j])
+			}
+		}
+	}
+}
+
+// writeMaterialData writes positions where side with extra piece wins.
+// Kings stay on their squares, so king relative features repeat often enough to be learned from few samples.
+func writeMaterialData(path string, count int, rng *rand.Rand) error {
+	var builder strings.Builder
+	pieces := []string{"Q", "R", "N"}
+	for i := 0; i < count; i++ {
+		square := rng.Intn(62)
+		if square >= E1 {
+			square++
+		}
+		if square >= E8 {
+			square++
+		}
+		board := make([]byte, 64)
+		for j := range board {
+			board[j] = '1'
+		}
+		strong := rng.Intn(2)
+		piece := pieces[rng.Intn(len(pieces))]
+		result := "1-0"
+		if strong == Black {
+			piece = strings.ToLower(piece)
+			result = "0-1"
+		}
+		board[E1], board[E8], board[square] = 'K', 'k', piece[0]
+		var rows []string
+		for rank := 7; rank >= 0; rank-- {
+			rows = append(rows, string(board[rank*8:rank*8+8]))
+		}
+		sideToMove := "wb"[rng.Intn(2)]
+		fmt.Fprintf(&builder, "%s %c - - 0 1;%s\n", strings.Join(rows, "/"), sideToMove, result)
+	}
+	return ioutil.WriteFile(path, []byte(builder.String()), 0644)
+}
+
+func TestTrain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "training")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	options := DefaultOptions()
+	options.DataPath = filepath.Join(dir, "data.fen")
+	options.NetworkPath = filepath.Join(dir, "network.nnue")
+	options.CheckpointPath = options.NetworkPath + ".checkpoint"
+	options.Epochs = 4
+	options.HalfDimensions = 16
+	options.HiddenDimensions = 4
+	options.BatchSize = 64
+	options.LearningRate = 0.01
+	options.Threads = 2
+	var progress bytes.Buffer
+	options.Progress = &progress
+	if err = writeMaterialData(options.DataPath, 2000, rand.New(rand.NewSource(1))); err != nil {
+		t.Fatal(err)
+	}
+	if err = Train(options); err != nil {
+		t.Fatal(err)
+	}
+
+	var losses []float64
+	for _, line := range strings.Split(progress.String(), "\n") {
+		var epoch int
+		var trainingLoss, validationLoss float64
+		if _, err := fmt.Sscanf(line, "Epoch %d training loss: %f validation loss: %f", &epoch, &trainingLoss, &validationLoss); err == nil {
+			losses = append(losses, validationLoss)
+		}
+	}
+	if len(losses) != options.Epochs || losses[len(losses)-1] >= losses[0] {
+		t.Fatalf("Expected decreasing validation loss, got %v", losses)
+	}
+
+	network, err := nnue.Load(options.NetworkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for fen, expected := range map[string]int{
+		"4k3/8/8/8/8/2Q5/8/4K3 w - - 0 1": 1,
+		"4k3/8/8/8/8/2Q5/8/4K3 b - - 0 1": -1,
+		"4k3/8/2r5/8/8/8/8/4K3 w - - 0 1": -1,
+	} {
+		pos := ParseFen(fen)
+		var acc nnue.Accumulator
+		for perspective, features := range positionFeatures(&pos) {
+			acc.Reset(network, perspective)
+			for _, feature := range features {
+				acc.Add(network, perspective, int(feature))
+			}
+		}
+		if score := network.Evaluate(&acc, pos.SideToMove); score*expected <= 0 {
+			t.Errorf("%s: unexpected evaluation %d", fen, score)
+		}
+	}
+
+	progress.Reset()
+	options.Epochs++
+	if err = Train(options); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(progress.String(), fmt.Sprintf("Resuming from epoch %d", options.Epochs-1)) ||
+		!strings.Contains(progress.String(), fmt.Sprintf("Epoch %d ", options.Epochs)) ||
+		strings.Contains(progress.String(), "Epoch 1 ") {
+		t.Errorf("Training was not resumed from checkpoint:\n%s", progress.String())
+	}
+
+	// Resumed training does not overwrite network with better validation loss
+	c, err := loadCheckpoint(options.CheckpointPath)
+	if err != nil || c.BestLoss <= 0 || math.IsInf(c.BestLoss, 1) {
+		t.Fatalf("Expected best loss in checkpoint, got %v", err)
+	}
+	c.BestLoss = 1e-9
+	if err = saveCheckpoint(options.CheckpointPath, c); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := ioutil.ReadFile(options.NetworkPath)
+	progress.Reset()
+	options.Epochs++
+	if err = Train(options); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := ioutil.ReadFile(options.NetworkPath); !bytes.Equal(saved, current) || strings.Contains(progress.String(), "Saved network") {
+		t.Errorf("Network with better validation loss was overwritten:\n%s", progress.String())
+	}
+}
@@ -1,12 +1,11 @@
 package transposition
 
+import "sync/atomic"
 import "github.com/mhib/combusken/backend"
 import . "github.com/mhib/combusken/utils"
 
 const NoneDepth = -6
 
-var GlobalTransTable TranspositionTable
-
 func ValueFromTrans(value int16, height int) int16 {
 	if value >= Mate-500 {
 		return value - int16(height)
@@ -30,13 +29,17 @@ func ValueToTrans(value int, height int) int16 {
 
 }
 
+// Entries are shared by threads without locks.
+// Data holds best move, value and eval, check holds key, flag and depth xored with mixed data,
+// so entry torn by concurrent writes is a miss, as in PerftHashTable.
 type transEntry struct {
-	key      uint32
-	bestMove backend.Move
-	value    int16
-	eval     int16
-	flag     uint8
-	depth    uint8
+	check uint64
+	data  uint64
+}
+
+// mix spreads every bit of data to upper half of word, where key is stored
+func mix(data uint64) uint64 {
+	return data * 0x9E3779B97F4A7C15
 }
 
 type TranspositionTable struct {
@@ -52,26 +55,26 @@ func (t *TranspositionTable) Clear() {
 
 func (t *TranspositionTable) Get(key uint64) (ok bool, value int16, eval int16, depth int16, move backend.Move, flag uint8) {
 	var element = &t.Entries[key&t.Mask]
-	if element.key != uint32(key>>32) {
+	data := atomic.LoadUint64(&element.data)
+	check := atomic.LoadUint64(&element.check) ^ mix(data)
+	if uint32(check>>32) != uint32(key>>32) {
 		return
 	}
 	ok = true
-	value = element.value
-	eval = element.eval
-	depth = int16(element.depth) + NoneDepth
-	move = element.bestMove
-	flag = element.flag
+	value = int16(data >> 32)
+	eval = int16(data >> 48)
+	depth = int16(uint8(check)) + NoneDepth
+	move = backend.Move(int32(uint32(data)))
+	flag = uint8(check >> 8)
 	return
 }
 
 func (t *TranspositionTable) Set(key uint64, value int16, eval int16, depth int, bestMove backend.Move, flag int) {
 	var element = &t.Entries[key&t.Mask]
-	element.key = uint32(key >> 32)
-	element.value = value
-	element.eval = eval
-	element.flag = uint8(flag)
-	element.depth = uint8(depth - NoneDepth)
-	element.bestMove = bestMove
+	data := uint64(uint32(bestMove)) | uint64(uint16(value))<<32 | uint64(uint16(eval))<<48
+	check := key&0xFFFFFFFF00000000 | uint64(uint8(flag))<<8 | uint64(uint8(depth-NoneDepth))
+	atomic.StoreUint64(&element.check, check^mix(data))
+	atomic.StoreUint64(&element.data, data)
 }
 
 func (t *TranspositionTable) Prefetch(key uint64) {
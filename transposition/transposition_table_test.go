@@ -0,0 +1,28 @@
+package transposition
+
+import (
+	"testing"
+
+	"github.com/mhib/combusken/backend"
+)
+
+func TestSetGet(t *testing.T) {
+	table := NewTransTable(1)
+	key := uint64(0x123456789ABCDEF0)
+	move := backend.NewMove(backend.E2, backend.E4, backend.Pawn, backend.None, 0)
+	table.Set(key, -31000, -120, NoneDepth, move, 2)
+	ok, value, eval, depth, bestMove, flag := table.Get(key)
+	if !ok || value != -31000 || eval != -120 || depth != NoneDepth || bestMove != move || flag != 2 {
+		t.Errorf("Unexpected entry %v %d %d %d %v %d", ok, value, eval, depth, bestMove, flag)
+	}
+	if ok, _, _, _, _, _ = table.Get(key ^ 1<<40); ok {
+		t.Error("Entry with different key is a hit")
+	}
+	// Entry with data written by another store is a miss
+	data := table.Entries[key&table.Mask].data
+	table.Set(key, -31000, -120, NoneDepth+1, move, 2)
+	table.Entries[key&table.Mask].data = data ^ 1
+	if ok, _, _, _, _, _ = table.Get(key); ok {
+		t.Error("Torn entry is a hit")
+	}
+}
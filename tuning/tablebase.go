@@ -0,0 +1,67 @@
+package tuning
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/fathom"
+)
+
+// Positions covered by Syzygy tablebases are labeled with tablebase result instead of game result.
+// Positions that are drawn according to tablebases, but come from decisive games are dropped.
+
+type tablebaseStats struct {
+	probed    int64
+	relabeled int64
+	dropped   int64
+}
+
+var tbStats tablebaseStats
+
+func setupTablebases(syzygyPath string) {
+	if syzygyPath == "" {
+		return
+	}
+	fathom.SetPath(syzygyPath)
+	fmt.Printf("Tablebases with up to %d pieces\n", fathom.MAX_PIECE_COUNT)
+}
+
+// tablebaseLabel returns result from white perspective for position covered by tablebases.
+// Returns false if position should not be used.
+func tablebaseLabel(pos *Position, result float64) (float64, bool) {
+	if fathom.MAX_PIECE_COUNT == 0 || pos.Flags != 0xF || pos.EpSquare != 0 ||
+		PopCount(pos.Colours[White]|pos.Colours[Black]) > fathom.MAX_PIECE_COUNT {
+		return result, true
+	}
+	wdl := fathom.ProbeWDL(pos, 0)
+	if wdl == fathom.TB_RESULT_FAILED {
+		return result, true
+	}
+	atomic.AddInt64(&tbStats.probed, 1)
+	label := 0.5
+	if wdl == fathom.TB_WIN {
+		label = 1.0
+	} else if wdl == fathom.TB_LOSS {
+		label = 0.0
+	}
+	if pos.SideToMove == Black {
+		label = 1.0 - label
+	}
+	if label == 0.5 && result != 0.5 {
+		atomic.AddInt64(&tbStats.dropped, 1)
+		return result, false
+	}
+	if label != result {
+		atomic.AddInt64(&tbStats.relabeled, 1)
+	}
+	return label, true
+}
+
+func printTablebaseStats() {
+	if fathom.MAX_PIECE_COUNT == 0 {
+		return
+	}
+	fmt.Printf("Tablebase positions: %d, relabeled: %d, dropped: %d\n",
+		atomic.LoadInt64(&tbStats.probed), atomic.LoadInt64(&tbStats.relabeled), atomic.LoadInt64(&tbStats.dropped))
+}
@@ -7,11 +7,11 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
-	"strings"
 	"sync"
 	"syscall"
 
 	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/dataset"
 	. "github.com/mhib/combusken/evaluation"
 	. "github.com/mhib/combusken/utils"
 )
@@ -147,22 +147,24 @@ func (t *traceTuner) calculateOptimalK() {
 	t.k = start
 }
 
-func TraceTune() {
+func TraceTune(syzygyPath string) {
+	setupTablebases(syzygyPath)
 	t := &traceTuner{done: false, batchSize: 16384 * 2}
 	t.weights = loadWeights()
 	t.bestWeights = make([]weight, len(t.weights))
 	copy(t.bestWeights, t.weights)
 
-	inputChan := make(chan string)
+	inputChan := make(chan *dataset.Record)
 	go loadEntries(inputChan)
 	var thread thread
-	for fen := range inputChan {
-		if entry, ok := t.parseTraceEntry(&thread, fen); ok {
+	for record := range inputChan {
+		if entry, ok := t.parseTraceEntry(&thread, record); ok {
 			t.entries = append(t.entries, entry)
 		}
 	}
 	fmt.Println("Number of entries:")
 	fmt.Println(len(t.entries))
+	printTablebaseStats()
 	t.calculateOptimalK()
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -214,19 +216,10 @@ func (t *traceTuner) regularization() float64 {
 	return sum * regularizationWeight
 }
 
-func (tuner *traceTuner) parseTraceEntry(t *thread, fen string) (traceEntry, bool) {
+func (tuner *traceTuner) parseTraceEntry(t *thread, record *dataset.Record) (traceEntry, bool) {
 	var res traceEntry
-	sepIdx := strings.Index(fen, ";")
-	boardFen := fen[:sepIdx]
-	score := fen[sepIdx+1:]
-	if strings.Contains(score, "1-0") {
-		res.result = 1.0
-	} else if strings.Contains(score, "0-1") {
-		res.result = 0.0
-	} else {
-		res.result = 0.5
-	}
-	board := ParseFen(boardFen)
+	res.result = record.WhiteResult()
+	board := record.Position
 	t.stack[0].position = board
 	t.quiescence(-Mate, Mate, 0, board.IsInCheck())
 	for _, move := range t.stack[0].pv.Moves() {
@@ -234,8 +227,12 @@ func (tuner *traceTuner) parseTraceEntry(t *thread, fen string) (traceEntry, boo
 		board.MakeMove(move, &child)
 		board = child
 	}
-	T = Trace{}
-	res.eval = float64(Evaluate(&board))
+	var ok bool
+	if res.result, ok = tablebaseLabel(&board, res.result); !ok {
+		return res, false
+	}
+	t.T = Trace{}
+	res.eval = float64(t.Evaluate(&board))
 
 	// Do not care about scaled positions
 	if ScaleFactor(&board, int16(res.eval)) != SCALE_NORMAL {
@@ -246,7 +243,7 @@ func (tuner *traceTuner) parseTraceEntry(t *thread, fen string) (traceEntry, boo
 		res.eval *= -1
 	}
 
-	for idx, val := range loadTrace() {
+	for idx, val := range loadTrace(&t.T) {
 		if val != 0 {
 			res.coefficients = append(res.coefficients, coefficient{idx: idx, value: val})
 		}
@@ -345,7 +342,7 @@ func (t *traceTuner) singleLinearDerivative(entry *traceEntry) float64 {
 	return -((entry.result - sigma) * sigmaPrim)
 }
 
-func loadTrace() (res []int) {
+func loadTrace(T *Trace) (res []int) {
 	res = append(res, T.PawnValue)
 	res = append(res, T.KnightValue)
 	res = append(res, T.BishopValue)
@@ -1,7 +1,6 @@
 package tuning
 
 import (
-	"bufio"
 	"fmt"
 	"math"
 	"math/rand"
@@ -9,11 +8,11 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"sync"
 	"syscall"
 
 	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/dataset"
 	"github.com/mhib/combusken/engine"
 	. "github.com/mhib/combusken/evaluation"
 	. "github.com/mhib/combusken/utils"
@@ -25,6 +24,7 @@ type tuneEntry struct {
 }
 
 type thread struct {
+	Evaluator
 	stack [127]stackEntry
 }
 
@@ -68,7 +68,7 @@ func (t *thread) quiescence(alpha, beta, height int, inCheck bool) int {
 
 	moveCount := 0
 
-	val := Evaluate(pos)
+	val := t.Evaluate(pos)
 
 	var evaled []EvaledMove
 	if inCheck {
@@ -121,8 +121,9 @@ type tuner struct {
 	done                    bool
 }
 
-func Tune() {
-	inputChan := make(chan string)
+func Tune(syzygyPath string) {
+	setupTablebases(syzygyPath)
+	inputChan := make(chan *dataset.Record)
 	go loadEntries(inputChan)
 	wg := &sync.WaitGroup{}
 	resultChan := make(chan tuneEntry)
@@ -131,8 +132,8 @@ func Tune() {
 		go func() {
 			defer wg.Done()
 			var t thread
-			for fen := range inputChan {
-				parseEntry(&t, fen, resultChan)
+			for record := range inputChan {
+				parseEntry(&t, record, resultChan)
 			}
 		}()
 	}
@@ -146,6 +147,7 @@ func Tune() {
 	}
 	fmt.Println("Number of entries:")
 	fmt.Println(len(t.entries))
+	printTablebaseStats()
 	t.calculateOptimalK()
 	fmt.Printf("Optimal k: %.17g\n", t.k)
 	sigs := make(chan os.Signal, 1)
@@ -189,9 +191,10 @@ func (t *tuner) computeError(entriesCount int) float64 {
 		go func(idx int) {
 			defer wg.Done()
 			var c, sum float64
+			var evaluator Evaluator
 			for y := idx; y < entriesCount; y += numCPU {
 				entry := t.entries[y]
-				evaluation := float64(Evaluate(&entry.Position))
+				evaluation := float64(evaluator.Evaluate(&entry.Position))
 				if entry.Position.SideToMove == Black {
 					evaluation *= -1
 				}
@@ -241,19 +244,10 @@ func (t *tuner) calculateOptimalK() {
 	t.k = start
 }
 
-func parseEntry(t *thread, fen string, resultChan chan tuneEntry) {
+func parseEntry(t *thread, record *dataset.Record, resultChan chan tuneEntry) {
 	var res tuneEntry
-	sepIdx := strings.Index(fen, ";")
-	boardFen := fen[:sepIdx]
-	score := fen[sepIdx+1:]
-	if strings.Contains(score, "1-0") {
-		res.result = 1.0
-	} else if strings.Contains(score, "0-1") {
-		res.result = 0.0
-	} else {
-		res.result = 0.5
-	}
-	board := ParseFen(boardFen)
+	res.result = record.WhiteResult()
+	board := record.Position
 	t.stack[0].position = board
 	t.quiescence(-Mate, Mate, 0, board.IsInCheck())
 	for _, move := range t.stack[0].pv.Moves() {
@@ -262,22 +256,29 @@ func parseEntry(t *thread, fen string, resultChan chan tuneEntry) {
 		board = child
 	}
 	res.Position = board
+	var ok bool
+	if res.result, ok = tablebaseLabel(&board, res.result); !ok {
+		return
+	}
 
 	resultChan <- res
 }
 
-func loadEntries(inputChan chan string) {
+// loadEntries reads games.bin file with packed records or games.fen text file when the former does not exist
+func loadEntries(inputChan chan *dataset.Record) {
 	defer close(inputChan)
-	absPath, _ := filepath.Abs("./games.fen")
-	file, err := os.Open(absPath)
+	path := "./games.bin"
+	if _, err := os.Stat(path); err != nil {
+		path = "./games.fen"
+	}
+	absPath, _ := filepath.Abs(path)
+	err := dataset.ReadFileParallel(absPath, runtime.NumCPU(), func(record *dataset.Record) {
+		res := *record
+		inputChan <- &res
+	})
 	if err != nil {
 		panic(err)
 	}
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		inputChan <- scanner.Text()
-	}
 }
 
 func sigmoid(K, S float64) float64 {
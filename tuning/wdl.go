@@ -0,0 +1,156 @@
+package tuning
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	. "github.com/mhib/combusken/backend"
+	"github.com/mhib/combusken/dataset"
+	. "github.com/mhib/combusken/evaluation"
+	. "github.com/mhib/combusken/utils"
+)
+
+// Parameters of win, draw and loss model are fitted by maximising likelihood of game results
+// with Adam optimiser. Score of a position is its search score if data contains it,
+// otherwise quiescence search score.
+
+type wdlEntry struct {
+	score    float64
+	material float64
+	// Game result from side to move perspective, dataset.WhiteWin is a win
+	result int
+}
+
+const wdlIterations = 3000
+const wdlLearningRate = 0.5
+
+func parseWDLEntry(t *thread, record *dataset.Record) (wdlEntry, bool) {
+	pos := record.Position
+	score := record.Score
+	if score == dataset.NoScore {
+		t.stack[0].position = pos
+		score = t.quiescence(-Mate, Mate, 0, pos.IsInCheck())
+	} else if pos.SideToMove == Black {
+		score = -score
+	}
+	// Known wins and mates do not tell anything about the model
+	if Abs(score) >= KnownWin {
+		return wdlEntry{}, false
+	}
+	result := record.Result
+	if pos.SideToMove == Black {
+		result = dataset.WhiteWin - result
+	}
+	return wdlEntry{float64(score), WDLMaterial(&pos), result}, true
+}
+
+// FitWDL fits parameters of win, draw and loss model to results of games from tuning data
+func FitWDL() {
+	inputChan := make(chan *dataset.Record)
+	go loadEntries(inputChan)
+	resultChan := make(chan wdlEntry)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var t thread
+			for record := range inputChan {
+				if entry, ok := parseWDLEntry(&t, record); ok {
+					resultChan <- entry
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+	var entries []wdlEntry
+	for entry := range resultChan {
+		entries = append(entries, entry)
+	}
+	fmt.Println("Number of entries:")
+	fmt.Println(len(entries))
+	if len(entries) == 0 {
+		return
+	}
+
+	model := DefaultWDLModel
+	parameters := []*float64{}
+	for i := range model.A {
+		parameters = append(parameters, &model.A[i], &model.B[i])
+	}
+	moments := make([]float64, len(parameters))
+	variances := make([]float64, len(parameters))
+	for iteration := 1; iteration <= wdlIterations; iteration++ {
+		loss, gradient := wdlGradient(&model, entries)
+		for i, parameter := range parameters {
+			moments[i] = 0.9*moments[i] + 0.1*gradient[i]
+			variances[i] = 0.999*variances[i] + 0.001*gradient[i]*gradient[i]
+			m := moments[i] / (1 - math.Pow(0.9, float64(iteration)))
+			v := variances[i] / (1 - math.Pow(0.999, float64(iteration)))
+			*parameter -= wdlLearningRate * m / (math.Sqrt(v) + 1e-8)
+		}
+		if iteration%100 == 0 {
+			fmt.Printf("Iteration %d loss: %.17g\n", iteration, loss)
+		}
+	}
+	fmt.Printf("var DefaultWDLModel = WDLModel{\n\tA: %#v,\n\tB: %#v,\n}\n", model.A, model.B)
+}
+
+// wdlGradient returns mean negative log likelihood of results and its gradient
+// with respect to coefficients of a and b interleaved
+func wdlGradient(model *WDLModel, entries []wdlEntry) (float64, []float64) {
+	numCPU := runtime.NumCPU()
+	losses := make([]float64, numCPU)
+	gradients := make([][]float64, numCPU)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < numCPU; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			gradient := make([]float64, 2*len(model.A))
+			for y := idx; y < len(entries); y += numCPU {
+				entry := &entries[y]
+				a, b := model.Parameters(entry.material)
+				win, draw, loss := model.Probabilities(int(entry.score), entry.material)
+				x := (entry.score - a) / b
+				z := (-entry.score - a) / b
+				// Derivatives of win and loss probabilities with respect to a and b
+				winA, winB := -win*(1-win)/b, -win*(1-win)*x/b
+				lossA, lossB := -loss*(1-loss)/b, -loss*(1-loss)*z/b
+				var probability, derivativeA, derivativeB float64
+				switch entry.result {
+				case dataset.WhiteWin:
+					probability, derivativeA, derivativeB = win, winA, winB
+				case dataset.BlackWin:
+					probability, derivativeA, derivativeB = loss, lossA, lossB
+				default:
+					probability, derivativeA, derivativeB = draw, -winA-lossA, -winB-lossB
+				}
+				probability = math.Max(probability, 1e-9)
+				losses[idx] -= math.Log(probability)
+				power := 1.0
+				for i := range model.A {
+					gradient[2*i] -= derivativeA * power / probability
+					gradient[2*i+1] -= derivativeB * power / probability
+					power *= entry.material
+				}
+			}
+			gradients[idx] = gradient
+		}(i)
+	}
+	wg.Wait()
+	var loss float64
+	gradient := make([]float64, 2*len(model.A))
+	for i := range losses {
+		loss += losses[i]
+		for j := range gradient {
+			gradient[j] += gradients[i][j] / float64(len(entries))
+		}
+	}
+	return loss / float64(len(entries)), gradient
+}
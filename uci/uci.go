@@ -8,6 +8,7 @@ import "context"
 
 import (
 	"bufio"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -45,7 +46,8 @@ func NewUciProtocol(e Engine) *UciProtocol {
 	return uci
 }
 
-func (uci *UciProtocol) Run() {
+// Run processes commands read from input until quit or end of input
+func (uci *UciProtocol) Run(input io.Reader) {
 	name, version, _ := uci.engine.GetInfo()
 	fmt.Printf("%v %v\n", name, version)
 	go func() {
@@ -54,7 +56,7 @@ func (uci *UciProtocol) Run() {
 			uci.state(msg)
 		}
 	}()
-	scanner := bufio.NewScanner(os.Stdin)
+	scanner := bufio.NewScanner(input)
 	for scanner.Scan() {
 		commandLine := scanner.Text()
 		if commandLine == "quit" {
@@ -94,15 +96,22 @@ func (uci *UciProtocol) thinking(msg interface{}) {
 		commandName := fields[0]
 		if commandName == "stop" {
 			uci.stopCommand()
+		} else if commandName == "isready" {
+			fmt.Println("readyok")
 		} else {
 			debugUci("Unexpected command " + commandName + ".")
 		}
 	case backend.Move:
 		fmt.Printf("bestmove %s\n", msg.String())
 		uci.state = uci.idle
+	case perftFinished:
+		uci.state = uci.idle
 	}
 }
 
+// perftFinished is sent after perft is finished or stopped
+type perftFinished struct{}
+
 func debugUci(s string) {
 	fmt.Println("info string " + s)
 }
@@ -166,6 +175,10 @@ func findIndexString(slice []string, value string) int {
 }
 
 func (uci *UciProtocol) goCommand(fields ...string) {
+	if len(fields) > 0 && fields[0] == "perft" {
+		uci.perftCommand(fields[1:]...)
+		return
+	}
 	limits := parseLimits(fields)
 	ctx, cancel := context.WithCancel(context.Background())
 	searchParams := SearchParams{
@@ -180,6 +193,30 @@ func (uci *UciProtocol) goCommand(fields ...string) {
 	}()
 }
 
+func (uci *UciProtocol) perftCommand(fields ...string) {
+	if len(fields) == 0 {
+		debugUci("Missing perft depth")
+		return
+	}
+	depth, err := strconv.Atoi(fields[0])
+	if err != nil || depth < 1 {
+		debugUci("Invalid perft depth")
+		return
+	}
+	// Perft is run like a search, so that it can be stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	uci.cancel = cancel
+	uci.state = uci.thinking
+	pos := uci.positions[len(uci.positions)-1]
+	threads := uci.engine.Threads.Val
+	go func() {
+		if _, err := backend.PrintDivide(ctx, os.Stdout, &pos, depth, threads, nil); err != nil {
+			debugUci("Perft stopped")
+		}
+		uci.messages <- perftFinished{}
+	}()
+}
+
 func parseLimits(args []string) (result LimitsType) {
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -223,6 +260,9 @@ func (uci *UciProtocol) uciNewGameCommand(...string) {
 	uci.waitChan = make(chan interface{})
 	defer close(uci.waitChan)
 	uci.engine.NewGame()
+	if info := uci.engine.EvaluationInfo(); info != "" {
+		debugUci(info)
+	}
 }
 
 func (uci *UciProtocol) ponderhitCommand(...string) {
@@ -237,12 +277,21 @@ func (uci *UciProtocol) stopCommand(...string) {
 
 func updateUci(s SearchInfo) {
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("info depth %d nodes %d score ", s.Depth, s.Nodes))
+	sb.WriteString(fmt.Sprintf("info depth %d seldepth %d nodes %d score ", s.Depth, s.SelDepth, s.Nodes))
 	if s.Score.Mate != 0 {
 		sb.WriteString(fmt.Sprintf("mate %d ", s.Score.Mate))
 	} else {
 		sb.WriteString(fmt.Sprintf("cp %d ", s.Score.Centipawn))
 	}
+	switch s.Bound {
+	case BoundLower:
+		sb.WriteString("lowerbound ")
+	case BoundUpper:
+		sb.WriteString("upperbound ")
+	}
+	if s.WDL != (WDLScore{}) {
+		sb.WriteString(fmt.Sprintf("wdl %d %d %d ", s.WDL.Win, s.WDL.Draw, s.WDL.Loss))
+	}
 	sb.WriteString(fmt.Sprintf("nps %d ", s.Nps))
 	sb.WriteString(fmt.Sprintf("time %d ", s.Duration))
 